@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+const IdentityContextKey = "identity"
+
+// Returned by providers for requests that don't carry any credentials for them, so the next provider can try
+var ErrNoCredentials = errors.New("no credentials for this provider")
+
+// Who is making a request, the scopes and policies are checked against it
+type Identity struct {
+	// the client_id or sub of a token
+	Id     string
+	Scopes []string
+	// name of the provider that authenticated the request
+	Provider string
+}
+
+// Authenticates requests to the api, UAA and OIDC issuers are providers
+type Provider interface {
+	Name() string
+	Authenticate(ctx echo.Context) (Identity, error)
+}
+
+// Lets requests through that one of the providers authenticates and puts their identity on the context. Requests
+// without credentials for any of them get a 400, requests with credentials that can't be verified the first provider's
+// error.
+func Middleware(skipper middleware.Skipper, providers ...Provider) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if skipper != nil && skipper(ctx) {
+				return next(ctx)
+			}
+
+			var authErr error
+			for _, provider := range providers {
+				identity, err := provider.Authenticate(ctx)
+				if err == nil {
+					ctx.Set(IdentityContextKey, identity)
+					return next(ctx)
+				}
+				if err != ErrNoCredentials && authErr == nil {
+					authErr = err
+				}
+			}
+			if authErr == nil {
+				return middleware.ErrJWTMissing
+			}
+			return authErr
+		}
+	}
+}
+
+// The identity the auth middleware put on the context, there is none when auth is disabled
+func GetIdentity(ctx echo.Context) (Identity, bool) {
+	identity, ok := ctx.Get(IdentityContextKey).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,57 @@
+package auth_test
+
+import (
+	"errors"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/labstack/echo"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeProvider struct {
+	name string
+	err  error
+}
+
+func (p fakeProvider) Name() string {
+	return p.name
+}
+
+func (p fakeProvider) Authenticate(ctx echo.Context) (auth.Identity, error) {
+	if p.err != nil {
+		return auth.Identity{}, p.err
+	}
+	return auth.Identity{Id: "director", Provider: p.name}, nil
+}
+
+var _ = Describe("Middleware", func() {
+	serve := func(providers ...auth.Provider) (auth.Identity, error) {
+		var identity auth.Identity
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/v1/data", nil), httptest.NewRecorder())
+		err := auth.Middleware(nil, providers...)(func(ctx echo.Context) error {
+			identity, _ = auth.GetIdentity(ctx)
+			return nil
+		})(ctx)
+		return identity, err
+	}
+
+	It("uses the identity of the first provider that authenticates the request", func() {
+		identity, err := serve(fakeProvider{name: "uaa", err: auth.ErrNoCredentials}, fakeProvider{name: "oidc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identity).To(Equal(auth.Identity{Id: "director", Provider: "oidc"}))
+	})
+
+	It("rejects requests without credentials with a 400", func() {
+		_, err := serve(fakeProvider{name: "uaa", err: auth.ErrNoCredentials})
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns the error of a provider that couldn't verify the credentials", func() {
+		invalid := errors.New("invalid")
+		_, err := serve(fakeProvider{name: "uaa", err: invalid}, fakeProvider{name: "oidc", err: auth.ErrNoCredentials})
+		Expect(err).To(Equal(invalid))
+	})
+})
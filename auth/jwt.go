@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"net/http"
+	"strings"
+)
+
+const BearerAuthScheme = "bearer" // uaa doesn't use "Bearer" (the JWT default), but "bearer"
+
+// Checks tokens signed with one of the keys in the key set
+type JwtVerifier struct {
+	Keys *KeySet
+	// the iss claim has to match when set
+	Issuer string
+	// one of the aud claims has to match, or only contain it with AudienceContains
+	Audience         string
+	AudienceContains bool
+}
+
+// The token of a bearer authorization header, the scheme is matched case insensitively
+func BearerToken(req *http.Request) (string, bool) {
+	authorization := req.Header.Get(echo.HeaderAuthorization)
+	schemeLength := len(BearerAuthScheme)
+	if len(authorization) <= schemeLength+1 || !strings.EqualFold(authorization[:schemeLength], BearerAuthScheme) || authorization[schemeLength] != ' ' {
+		return "", false
+	}
+	return authorization[schemeLength+1:], true
+}
+
+// Validates the signature and expiry of the token, then its issuer and audience
+func (v *JwtVerifier) Verify(rawToken string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(rawToken, v.Keys.KeyFunc)
+	if err != nil || !token.Valid {
+		logger.Log.Debugf("invalid jwt: %s", err)
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+
+	if v.Issuer != "" {
+		issuer, _ := claims["iss"].(string)
+		if issuer != v.Issuer {
+			errorText := fmt.Sprintf("valid JWT received but issued by %q instead of %q", issuer, v.Issuer)
+			logger.Log.Error(errorText)
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, errorText)
+		}
+	}
+
+	if !v.validAudience(Audiences(claims)) {
+		errorText := fmt.Sprintf("valid JWT received but missing %s audience claim, closing connection", v.Audience)
+		logger.Log.Error(errorText)
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, errorText)
+	}
+	return claims, nil
+}
+
+func (v *JwtVerifier) validAudience(audiences []string) bool {
+	for _, audience := range audiences {
+		if audience == v.Audience || (v.AudienceContains && strings.Contains(audience, v.Audience)) {
+			return true
+		}
+	}
+	return false
+}
+
+// The aud claim is a list when the token is meant for several resources, or a single string
+func Audiences(claims jwt.MapClaims) []string {
+	audiences := make([]string, 0)
+	switch audClaim := claims["aud"].(type) {
+	case string:
+		audiences = append(audiences, audClaim)
+	case []interface{}:
+		for _, audience := range audClaim {
+			if audienceString, ok := audience.(string); ok {
+				audiences = append(audiences, audienceString)
+			}
+		}
+	}
+	return audiences
+}
+
+// The identity of a verified token
+func TokenIdentity(provider string, claims jwt.MapClaims) Identity {
+	return Identity{
+		Id:       ClientId(claims),
+		Scopes:   Scopes(claims),
+		Provider: provider,
+	}
+}
@@ -0,0 +1,97 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jwt", func() {
+	var rsaKey *rsa.PrivateKey
+	var ecKey *ecdsa.PrivateKey
+	var jwksServer *httptest.Server
+	var verifier *auth.JwtVerifier
+
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.Bytes())
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		Expect(err).ToNot(HaveOccurred())
+		return signed
+	}
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		jwksServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(auth.Jwks{Keys: []auth.Jwk{
+				{Kid: "rsa", Alg: "RS256", Kty: "RSA", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+				{Kid: "ec", Alg: "ES256", Kty: "EC", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+				{Kid: "encryption", Kty: "RSA", Use: "enc", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+			}})
+		}))
+
+		keys := auth.NewKeySet(jwksServer.URL, http.DefaultClient)
+		Expect(keys.Refresh()).To(Succeed())
+		verifier = &auth.JwtVerifier{
+			Keys:     keys,
+			Issuer:   "https://issuer.example.com",
+			Audience: "bosh-vault",
+		}
+	})
+
+	AfterEach(func() {
+		jwksServer.Close()
+	})
+
+	It("accepts the audience as a string or a list", func() {
+		_, err := verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "bosh-vault"}))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": []interface{}{"other", "bosh-vault"}}))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "bosh-vault-2"}))
+		Expect(err).To(HaveOccurred())
+		_, err = verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com"}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only needs an audience containing the expected one when asked to", func() {
+		verifier.AudienceContains = true
+		_, err := verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "bosh-vault-2"}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("checks the issuer", func() {
+		_, err := verifier.Verify(sign(jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{"iss": "https://other.example.com", "aud": "bosh-vault"}))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads elliptic curve keys and skips keys that aren't for signing", func() {
+		_, err := verifier.Verify(sign(jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "bosh-vault"}))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = verifier.Verify(sign(jwt.SigningMethodRS256, "encryption", rsaKey, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "bosh-vault"}))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the signing keys can be fetched again when a token is signed with a key that isn't known yet
+var UnknownKeyRefreshInterval = 30 * time.Second
+
+// A signing key as listed by a JWKS endpoint, UAA lists RSA keys with a PEM value too
+// @see: https://tools.ietf.org/html/rfc7517
+type Jwk struct {
+	Kid   string `json:"kid"`
+	Alg   string `json:"alg"`
+	Value string `json:"value"`
+	Kty   string `json:"kty"`
+	Use   string `json:"use"`
+	N     string `json:"n"`
+	E     string `json:"e"`
+	Crv   string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+type signingKey struct {
+	alg string
+	key interface{}
+}
+
+// The signing keys of an issuer fetched from its JWKS endpoint
+type KeySet struct {
+	Url        string
+	httpClient *http.Client
+
+	// signing keys by kid, replaced whenever they're fetched again
+	keysMutex   sync.RWMutex
+	signingKeys map[string]signingKey
+	// serializes fetching the keys
+	refreshMutex sync.Mutex
+	lastRefresh  time.Time
+}
+
+func NewKeySet(url string, httpClient *http.Client) *KeySet {
+	return &KeySet{
+		Url:        url,
+		httpClient: httpClient,
+	}
+}
+
+// A client trusting the CA in the file as well as the system's trusted certs
+func NewHttpClient(ca string, skipVerify bool, timeoutSeconds int) (*http.Client, error) {
+	// Get the SystemCertPool, continue with an empty pool on error
+	rootCAs, _ := x509.SystemCertPool()
+	if rootCAs == nil {
+		logger.Log.Error("problem reading system cert pool, if no CA cert was passed in the config expect TLS errors")
+		rootCAs = x509.NewCertPool()
+	}
+
+	if ca != "" {
+		certs, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("failed to append %q to RootCAs: %v", ca, err)
+		}
+
+		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+			logger.Log.Errorf("no certs appended from %s, using system certs only", ca)
+		}
+	}
+
+	return &http.Client{
+		Timeout: time.Second * time.Duration(timeoutSeconds),
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: skipVerify,
+			RootCAs:            rootCAs,
+		}},
+	}, nil
+}
+
+func (k *KeySet) Refresh() error {
+	k.refreshMutex.Lock()
+	defer k.refreshMutex.Unlock()
+	return k.fetch()
+}
+
+// Fetches the keys again periodically, this will cut down on traffic to the issuer. Tokens signed with a new key fetch
+// the keys right away.
+func (k *KeySet) RefreshEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			logger.Log.Debugf("refreshing signing key info from %s", k.Url)
+			err := k.Refresh()
+			if err != nil {
+				logger.Log.Errorf("error getting signing key info from %s, perhaps it's down? continuing to use cached signing key data...", k.Url)
+			}
+		}
+	}()
+}
+
+// Fetches the keys again for a token signed with an unknown key, unless they were fetched too recently
+func (k *KeySet) refreshForUnknownKey(kid string) {
+	k.refreshMutex.Lock()
+	defer k.refreshMutex.Unlock()
+
+	if time.Since(k.lastRefresh) < UnknownKeyRefreshInterval {
+		return
+	}
+	logger.Log.Infof("token signed with unknown key %s, refreshing signing key info from %s", kid, k.Url)
+	err := k.fetch()
+	if err != nil {
+		logger.Log.Errorf("error getting signing key info from %s: %s", k.Url, err)
+	}
+}
+
+// Callers hold the refresh mutex
+func (k *KeySet) fetch() error {
+	var jwks Jwks
+
+	// failed attempts count towards the rate limit too so an unreachable issuer isn't asked on every request
+	k.lastRefresh = time.Now()
+
+	resp, err := k.httpClient.Get(k.Url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received a status code %v when requesting token signing info", resp.Status)
+	}
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(responseBody, &jwks)
+	if err != nil {
+		return err
+	}
+
+	signingKeys := make(map[string]signingKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJwk(jwk)
+		if err != nil {
+			logger.Log.Errorf("ignoring signing key %s: %s", jwk.Kid, err)
+			continue
+		}
+		signingKeys[jwk.Kid] = signingKey{
+			alg: jwk.Alg,
+			key: key,
+		}
+	}
+	if len(signingKeys) == 0 {
+		return fmt.Errorf("no usable signing keys in the response from %s", k.Url)
+	}
+
+	// cache response
+	k.keysMutex.Lock()
+	k.signingKeys = signingKeys
+	k.keysMutex.Unlock()
+
+	return nil
+}
+
+func (k *KeySet) signingKey(kid string) (signingKey, bool) {
+	k.keysMutex.RLock()
+	defer k.keysMutex.RUnlock()
+
+	// tokens without a kid can only be checked when there's a single key
+	if kid == "" && len(k.signingKeys) == 1 {
+		for _, key := range k.signingKeys {
+			return key, true
+		}
+	}
+	key, ok := k.signingKeys[kid]
+	return key, ok
+}
+
+// Picks the key a token was signed with by its kid
+func (k *KeySet) KeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.signingKey(kid)
+	if !ok {
+		// another request may have fetched the key while this one waited
+		k.refreshForUnknownKey(kid)
+		key, ok = k.signingKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+	if key.alg != "" && key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("signing key %s is for %s but the token is signed with %s", kid, key.alg, token.Method.Alg())
+	}
+	return key.key, nil
+}
+
+func parseJwk(jwk Jwk) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA", "":
+		if jwk.Value != "" {
+			return jwt.ParseRSAPublicKeyFromPEM([]byte(jwk.Value))
+		}
+		return rsaPublicKey(jwk.N, jwk.E)
+	case "EC":
+		if jwk.Value != "" {
+			return jwt.ParseECPublicKeyFromPEM([]byte(jwk.Value))
+		}
+		return ecPublicKey(jwk.Crv, jwk.X, jwk.Y)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+// Builds a key from its JWK modulus and exponent, for keys listed without a PEM value
+func rsaPublicKey(encodedModulus string, encodedExponent string) (*rsa.PublicKey, error) {
+	modulus, err := decodeJwkInt(encodedModulus)
+	if err != nil {
+		return nil, err
+	}
+	exponent, err := decodeJwkInt(encodedExponent)
+	if err != nil {
+		return nil, err
+	}
+	if modulus.Sign() == 0 || exponent.Sign() == 0 {
+		return nil, fmt.Errorf("missing modulus or exponent")
+	}
+	return &rsa.PublicKey{
+		N: modulus,
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+// Builds a key from its JWK curve and coordinates
+func ecPublicKey(crv string, encodedX string, encodedY string) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", crv)
+	}
+	x, err := decodeJwkInt(encodedX)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJwkInt(encodedY)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("point isn't on the %s curve", crv)
+	}
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
+
+func decodeJwkInt(encoded string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"net/http"
+	"strings"
+)
+
+// Only lets requests through whose identity has at least one of the scopes required by the provider that
+// authenticated it, any identity is enough for a provider without scopes. It has to run after Middleware, which puts the
+// identity on the context.
+func RequireScopes(scopesByProvider map[string][]string) echo.MiddlewareFunc {
+	required := false
+	for _, scopes := range scopesByProvider {
+		required = required || len(scopes) > 0
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !required {
+				return next(ctx)
+			}
+
+			identity, ok := GetIdentity(ctx)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing or malformed jwt")
+			}
+			scopes := scopesByProvider[identity.Provider]
+			if len(scopes) == 0 {
+				return next(ctx)
+			}
+			for _, scope := range scopes {
+				for _, identityScope := range identity.Scopes {
+					if scope == identityScope {
+						return next(ctx)
+					}
+				}
+			}
+
+			errorText := fmt.Sprintf("token for %s is missing one of the scopes %s", identity.Id, strings.Join(scopes, ", "))
+			logger.Log.Error(errorText)
+			return echo.NewHTTPError(http.StatusForbidden, errorText)
+		}
+	}
+}
+
+// The scopes granted by the token, UAA puts them in a list but they can also be a space separated string
+func Scopes(claims jwt.MapClaims) []string {
+	scopes := make([]string, 0)
+	switch scopeClaim := claims["scope"].(type) {
+	case string:
+		scopes = strings.Fields(scopeClaim)
+	case []interface{}:
+		for _, scope := range scopeClaim {
+			if scopeString, ok := scope.(string); ok {
+				scopes = append(scopes, scopeString)
+			}
+		}
+	}
+	return scopes
+}
+
+// Who the token was issued to, the client for client credentials tokens or the user otherwise
+func ClientId(claims jwt.MapClaims) string {
+	if clientId, ok := claims["client_id"].(string); ok && clientId != "" {
+		return clientId
+	}
+	subject, _ := claims["sub"].(string)
+	return subject
+}
@@ -0,0 +1,64 @@
+package auth_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scopes", func() {
+	serve := func(scopes map[string][]string, identity *auth.Identity) error {
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/v1/data", nil), httptest.NewRecorder())
+		if identity != nil {
+			ctx.Set(auth.IdentityContextKey, *identity)
+		}
+		return auth.RequireScopes(scopes)(func(ctx echo.Context) error {
+			return nil
+		})(ctx)
+	}
+
+	identityWithScopes := func(provider string, scopes ...string) *auth.Identity {
+		return &auth.Identity{
+			Id:       "monitoring",
+			Scopes:   scopes,
+			Provider: provider,
+		}
+	}
+
+	It("reads scopes from a list or a space separated string", func() {
+		Expect(auth.Scopes(jwt.MapClaims{"scope": []interface{}{"config_server.read", "config_server.write"}})).To(Equal([]string{"config_server.read", "config_server.write"}))
+		Expect(auth.Scopes(jwt.MapClaims{"scope": "config_server.read config_server.write"})).To(Equal([]string{"config_server.read", "config_server.write"}))
+		Expect(auth.Scopes(jwt.MapClaims{})).To(BeEmpty())
+	})
+
+	It("lets identities with one of the scopes through", func() {
+		identity := identityWithScopes("uaa", "openid", "config_server.read")
+		Expect(serve(map[string][]string{"uaa": {"config_server.admin", "config_server.read"}}, identity)).To(Succeed())
+	})
+
+	It("forbids identities without any of the scopes", func() {
+		err := serve(map[string][]string{"uaa": {"config_server.write"}}, identityWithScopes("uaa", "config_server.read"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("checks the scopes of the provider that authenticated the request", func() {
+		scopes := map[string][]string{"uaa": {"config_server.read"}, "oidc": {"bosh.read"}}
+		Expect(serve(scopes, identityWithScopes("oidc", "bosh.read"))).To(Succeed())
+		Expect(serve(scopes, identityWithScopes("oidc", "config_server.read"))).ToNot(Succeed())
+		Expect(serve(map[string][]string{"uaa": {"config_server.read"}}, identityWithScopes("oidc"))).To(Succeed())
+	})
+
+	It("doesn't need an identity when no scopes are required", func() {
+		Expect(serve(nil, nil)).To(Succeed())
+
+		err := serve(map[string][]string{"uaa": {"config_server.read"}}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusUnauthorized))
+	})
+})
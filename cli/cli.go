@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	"io"
+	"os"
+	"strings"
+)
+
+// Operations that can be run against the configured store directly instead of starting the api server
+const BulkRegenerateCommand = "bulk-regenerate"
+const CertificatesCommand = "certificates"
+const RewrapCommand = "rewrap"
+
+var Commands = []string{BulkRegenerateCommand, CertificatesCommand, RewrapCommand}
+
+func Run(bvConfig config.Configuration, args []string) error {
+	if len(args) == 0 {
+		return errors.New(fmt.Sprintf("no command passed, must be one of: %s", strings.Join(Commands, ", ")))
+	}
+
+	switch args[0] {
+	case BulkRegenerateCommand:
+		return bulkRegenerate(bvConfig, args[1:], os.Stdout)
+	case CertificatesCommand:
+		return certificates(bvConfig, args[1:], os.Stdout)
+	case RewrapCommand:
+		return rewrap(bvConfig, args[1:], os.Stdout)
+	default:
+		return errors.New(fmt.Sprintf("unknown command: %s, must be one of: %s", args[0], strings.Join(Commands, ", ")))
+	}
+}
+
+func bulkRegenerate(bvConfig config.Configuration, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(BulkRegenerateCommand, flag.ContinueOnError)
+	signedBy := flags.String("signed-by", "", "name of the CA whose certificates should be regenerated")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *signedBy == "" {
+		return errors.New("-signed-by is required")
+	}
+
+	secretStore := store.GetStore(bvConfig)
+	defer secretStore.Close()
+
+	bulkRegenerateResponse, err := types.BulkRegenerate(context.Background(), secretStore, *signedBy)
+	if err != nil {
+		return err
+	}
+
+	return printJson(out, bulkRegenerateResponse)
+}
+
+func certificates(bvConfig config.Configuration, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(CertificatesCommand, flag.ContinueOnError)
+	expiresWithinDays := flags.Int("expires-within-days", 0, "only list certificates that expire within this many days")
+	signedBy := flags.String("signed-by", "", "only list certificates signed by this CA")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	secretStore := store.GetStore(bvConfig)
+	defer secretStore.Close()
+
+	inventoryResponse, err := types.CertificateInventory(secretStore, types.CertificateInventoryRequest{
+		ExpiresWithinDays: *expiresWithinDays,
+		SignedBy:          *signedBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printJson(out, inventoryResponse)
+}
+
+func rewrap(bvConfig config.Configuration, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(RewrapCommand, flag.ContinueOnError)
+	path := flags.String("path", "", "rewrap the credentials under this path, use / for every credential")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	// every rewrapped credential gets a new id, deployments using it update their instances on the next deploy
+	if *path == "" {
+		return errors.New("rewrap needs -path, rewrapped credentials get a new version which the next deploy of every deployment using them rolls out")
+	}
+
+	secretStore := store.GetStore(bvConfig)
+	defer secretStore.Close()
+
+	transitStore, ok := secretStore.(*store.TransitStore)
+	if !ok {
+		return errors.New("transit encryption isn't configured, set store.transit.key to use it")
+	}
+
+	rewrapResult, err := transitStore.Rewrap(*path)
+	if err != nil {
+		return err
+	}
+
+	return printJson(out, rewrapResult)
+}
+
+func printJson(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/micro/go-config"
 	"github.com/micro/go-config/source/env"
 	"github.com/micro/go-config/source/file"
@@ -9,16 +11,53 @@ import (
 const DefaultApiListenAddress = "0.0.0.0:1337"
 const DefaultLogLevel = "ERROR"
 const DefaultShutdownTimeoutSeconds = 30
+const DefaultBulkRegenerateTimeoutSeconds = 300
 const DefaultUaaConnectionTimeoutSeconds = 10
 const DefaultUaaAudienceClaim = "config_server"
 const DefaultUaaKeyRefreshIntervalSeconds = 86400
+const DefaultOidcConnectionTimeoutSeconds = 10
+const DefaultOidcKeyRefreshIntervalSeconds = 86400
+
+// The authority of the director's config server client, every kind of request needs it unless scopes are configured
+const DefaultScope = "config_server.admin"
 const DefaultVaultConnectionTimeoutSeconds = 30
 const DefaultVaultMount = "secret"
 
+// How deleting a credential treats its versions in the KV2 mount
+const DeletePolicyLatestSoft = "latest-soft"           // soft delete only the latest version
+const DeletePolicyAllSoft = "all-soft"                 // soft delete every version, they can still be undeleted
+const DeletePolicyMetadataDestroy = "metadata-destroy" // remove the metadata and with it every version permanently
+const DefaultVaultDeletePolicy = DeletePolicyAllSoft
+
+// How requests to the api are authenticated, a request is let through when any of the configured providers accepts it
+const AuthProviderUaa = "uaa"
+const AuthProviderOidc = "oidc"
+const AuthProviderMtls = "mtls" // client certificates signed by the tls client CA
+
+// What part of a client certificate identifies the client
+const MtlsIdentityCommonName = "commonname"
+const MtlsIdentityDnsName = "dnsname" // the first DNS SAN
+const MtlsIdentityUri = "uri"         // the first URI SAN
+const MtlsIdentityEmail = "email"     // the first email SAN
+const DefaultMtlsIdentity = MtlsIdentityCommonName
+
+// Where credentials are stored, see store.RegisterBackend
+const StoreBackendVault = "vault"
+const StoreBackendFile = "file"
+const DefaultStoreBackend = StoreBackendVault
+
+const DefaultTransitMount = "transit"
+
+const VaultAuthMethodToken = "token"
+const VaultAuthMethodAppRole = "approle"
+const VaultAuthMethodCert = "cert"
+const VaultAuthMethodKubernetes = "kubernetes"
+
 type Configuration struct {
 	Api struct {
-		Address      string `json:"address" yaml:"address"`
-		DrainTimeout int    `json:"draintimeout" yaml:"draintimeout"`
+		Address               string `json:"address" yaml:"address"`
+		DrainTimeout          int    `json:"draintimeout" yaml:"draintimeout"`
+		BulkRegenerateTimeout int    `json:"bulkregeneratetimeout" yaml:"bulkregeneratetimeout"`
 	} `json:"api" yaml:"api"`
 	Log struct {
 		Level string `json:"level" yaml:"level"`
@@ -26,11 +65,65 @@ type Configuration struct {
 	Tls struct {
 		Cert string `json:"cert" yaml:"key"`
 		Key  string `json:"key" yaml:"key"`
+		// CA bundle client certificates are verified with for the mtls provider
+		ClientCa string `json:"clientca" yaml:"clientca"`
 	} `json:"tls" yaml:"tls"`
-	Redirects []RedirectBlock    `json:"redirects" yaml:"redirects"`
-	Uaa       UaaConfiguration   `json:"uaa" yaml:"uaa"`
-	Vault     VaultConfiguration `json:"vault" yaml:"vault"`
-	Debug     DebugConfiguration `json:"debug" yaml:"debug"`
+	Redirects []RedirectBlock       `json:"redirects" yaml:"redirects"`
+	Policies  []PolicyConfiguration `json:"policies" yaml:"policies"`
+	Auth      AuthConfiguration     `json:"auth" yaml:"auth"`
+	Uaa       UaaConfiguration      `json:"uaa" yaml:"uaa"`
+	Oidc      OidcConfiguration     `json:"oidc" yaml:"oidc"`
+	Mtls      MtlsConfiguration     `json:"mtls" yaml:"mtls"`
+	Store     StoreConfiguration    `json:"store" yaml:"store"`
+	Vault     VaultConfiguration    `json:"vault" yaml:"vault"`
+	Debug     DebugConfiguration    `json:"debug" yaml:"debug"`
+}
+
+type StoreConfiguration struct {
+	Backend string                 `json:"backend" yaml:"backend"`
+	File    FileStoreConfiguration `json:"file" yaml:"file"`
+	Transit TransitConfiguration   `json:"transit" yaml:"transit"`
+}
+
+// Encrypts credentials with a transit key of the default Vault before they're written to the KV mount, only credentials
+// matching a rule are encrypted and every credential is when there are no rules
+type TransitConfiguration struct {
+	Mount string        `json:"mount" yaml:"mount"`
+	Key   string        `json:"key" yaml:"key"`
+	Rules []TransitRule `json:"rules" yaml:"rules"`
+}
+
+// A credential matches when its name starts with the prefix and it is one of the types, empty matches anything
+type TransitRule struct {
+	Prefix string   `json:"prefix" yaml:"prefix"`
+	Types  []string `json:"types" yaml:"types"`
+}
+
+// The file backend keeps every credential in a single file encrypted with a key derived from the passphrase in keyfile
+type FileStoreConfiguration struct {
+	Path         string `json:"path" yaml:"path"`
+	KeyFile      string `json:"keyfile" yaml:"keyfile"`
+	DeletePolicy string `json:"deletepolicy" yaml:"deletepolicy"`
+}
+
+// Operations a policy can allow on the credentials under a path
+const PolicyOperationRead = "read"
+const PolicyOperationWrite = "write"
+const PolicyOperationDelete = "delete"
+const PolicyOperationSign = "sign" // generate certificates signed by the CA without being able to read it
+
+// Limits the clients, matched by the client_id or sub of their token or the identity of their certificate, to the listed
+// paths. Without any policies every client may do anything, with policies a client without one may do nothing.
+type PolicyConfiguration struct {
+	// the auth provider the clients are authenticated by, uaa when it isn't set
+	Provider string       `json:"provider" yaml:"provider"`
+	Clients  []string     `json:"clients" yaml:"clients"`
+	Paths    []PolicyPath `json:"paths" yaml:"paths"`
+}
+
+type PolicyPath struct {
+	Prefix     string   `json:"prefix" yaml:"prefix"`
+	Operations []string `json:"operations" yaml:"operations"`
 }
 
 type DebugConfiguration struct {
@@ -38,28 +131,84 @@ type DebugConfiguration struct {
 	DisableTls  bool `json:"disable_tls" yaml:"disable_tls"`
 }
 
+type AuthConfiguration struct {
+	Providers []string `json:"providers" yaml:"providers"`
+}
+
 type UaaConfiguration struct {
-	Address               string `json:"address" yaml:"address"`
-	Timeout               int    `json:"timeout" yaml:"timeout"`
-	Ca                    string `json:"ca" yaml:"ca"`
-	SkipVerify            bool   `json:"skipverify" yaml:"skipverify"`
-	ExpectedAudienceClaim string `json:"audienceclaim"`
-	KeyRefreshInterval    int    `json:"keyrefreshinterval" yaml:"keyrefreshinterval"`
+	Address               string             `json:"address" yaml:"address"`
+	Timeout               int                `json:"timeout" yaml:"timeout"`
+	Ca                    string             `json:"ca" yaml:"ca"`
+	SkipVerify            bool               `json:"skipverify" yaml:"skipverify"`
+	ExpectedAudienceClaim string             `json:"audienceclaim"`
+	KeyRefreshInterval    int                `json:"keyrefreshinterval" yaml:"keyrefreshinterval"`
+	Scopes                ScopeConfiguration `json:"scopes" yaml:"scopes"`
+}
+
+// Any OpenID Connect issuer, its signing keys are found through its discovery document
+type OidcConfiguration struct {
+	Issuer             string             `json:"issuer" yaml:"issuer"`
+	Audience           string             `json:"audience" yaml:"audience"`
+	Timeout            int                `json:"timeout" yaml:"timeout"`
+	Ca                 string             `json:"ca" yaml:"ca"`
+	SkipVerify         bool               `json:"skipverify" yaml:"skipverify"`
+	KeyRefreshInterval int                `json:"keyrefreshinterval" yaml:"keyrefreshinterval"`
+	Scopes             ScopeConfiguration `json:"scopes" yaml:"scopes"`
+}
+
+type MtlsConfiguration struct {
+	Identity string `json:"identity" yaml:"identity"`
+}
+
+// A token needs one of the listed scopes for each kind of request, an empty list lets any valid token through
+type ScopeConfiguration struct {
+	Read   []string `json:"read" yaml:"read"`
+	Write  []string `json:"write" yaml:"write"`
+	Delete []string `json:"delete" yaml:"delete"`
+}
+
+// Every kind of request defaults to DefaultScope, each slice is its own so scanning the config file into one doesn't
+// change the others
+func defaultScopes() ScopeConfiguration {
+	return ScopeConfiguration{
+		Read:   []string{DefaultScope},
+		Write:  []string{DefaultScope},
+		Delete: []string{DefaultScope},
+	}
 }
 
 type VaultConfiguration struct {
-	Address         string `json:"address" yaml:"address"`
-	Token           string `json:"token" yaml:"token"`
-	Timeout         int    `json:"timeout" yaml:"timeout"`
-	Mount           string `json:"mount" yaml:"mount"`
-	Ca              string `json:"ca" yaml:"ca"`
-	SkipVerify      bool   `json:"skipverify" yaml:"skipverify"`
-	RenewalInterval int    `json:"renewalinterval" yaml:"renewalinterval"`
+	Address         string                 `json:"address" yaml:"address"`
+	Token           string                 `json:"token" yaml:"token"`
+	TokenFile       string                 `json:"tokenfile" yaml:"tokenfile"`
+	Timeout         int                    `json:"timeout" yaml:"timeout"`
+	Mount           string                 `json:"mount" yaml:"mount"`
+	CreateMount     bool                   `json:"createmount" yaml:"createmount"`
+	KVVersion       int                    `json:"kvversion" yaml:"kvversion"`
+	Ca              string                 `json:"ca" yaml:"ca"`
+	SkipVerify      bool                   `json:"skipverify" yaml:"skipverify"`
+	RenewalInterval int                    `json:"renewalinterval" yaml:"renewalinterval"`
+	DeletePolicy    string                 `json:"deletepolicy" yaml:"deletepolicy"`
+	Auth            VaultAuthConfiguration `json:"auth" yaml:"auth"`
+	Namespace       string                 `json:"namespace" yaml:"namespace"`
+}
+
+// Without an auth method the static token from the Vault configuration is used
+type VaultAuthConfiguration struct {
+	Method       string `json:"method" yaml:"method"`
+	Mount        string `json:"mount" yaml:"mount"`
+	Role         string `json:"role" yaml:"role"`
+	RoleId       string `json:"roleid" yaml:"roleid"`
+	SecretIdFile string `json:"secretidfile" yaml:"secretidfile"`
+	CertFile     string `json:"certfile" yaml:"certfile"`
+	KeyFile      string `json:"keyfile" yaml:"keyfile"`
+	JwtFile      string `json:"jwtfile" yaml:"jwtfile"`
 }
 
 type RedirectRule struct {
-	Ref      string `json:"ref" yaml:"ref"`
-	Redirect string `json:"redirect" yaml:"redirect"`
+	Ref       string `json:"ref" yaml:"ref"`
+	Redirect  string `json:"redirect" yaml:"redirect"`
+	Namespace string `json:"namespace" yaml:"namespace"`
 }
 
 type RedirectBlock struct {
@@ -73,11 +222,22 @@ func ParseConfig(configFilePath *string) Configuration {
 	bvConfig.Api.Address = DefaultApiListenAddress
 	bvConfig.Log.Level = DefaultLogLevel
 	bvConfig.Api.DrainTimeout = DefaultShutdownTimeoutSeconds
+	bvConfig.Api.BulkRegenerateTimeout = DefaultBulkRegenerateTimeoutSeconds
 	bvConfig.Uaa.Timeout = DefaultUaaConnectionTimeoutSeconds
 	bvConfig.Uaa.ExpectedAudienceClaim = DefaultUaaAudienceClaim
 	bvConfig.Uaa.KeyRefreshInterval = DefaultUaaKeyRefreshIntervalSeconds
+	bvConfig.Uaa.Scopes = defaultScopes()
+	bvConfig.Auth.Providers = []string{AuthProviderUaa}
+	bvConfig.Oidc.Timeout = DefaultOidcConnectionTimeoutSeconds
+	bvConfig.Oidc.KeyRefreshInterval = DefaultOidcKeyRefreshIntervalSeconds
+	bvConfig.Oidc.Scopes = defaultScopes()
+	bvConfig.Mtls.Identity = DefaultMtlsIdentity
 	bvConfig.Vault.Timeout = DefaultVaultConnectionTimeoutSeconds
 	bvConfig.Vault.Mount = DefaultVaultMount
+	bvConfig.Vault.DeletePolicy = DefaultVaultDeletePolicy
+	bvConfig.Store.Backend = DefaultStoreBackend
+	bvConfig.Store.File.DeletePolicy = DefaultVaultDeletePolicy
+	bvConfig.Store.Transit.Mount = DefaultTransitMount
 
 	if configFilePath == nil || *configFilePath == "" {
 		return bvConfig
@@ -92,3 +252,13 @@ func ParseConfig(configFilePath *string) Configuration {
 		return bvConfig
 	}
 }
+
+// An empty delete policy is valid, it means the default one
+func ValidateDeletePolicy(deletePolicy string) error {
+	switch deletePolicy {
+	case "", DeletePolicyLatestSoft, DeletePolicyAllSoft, DeletePolicyMetadataDestroy:
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("unknown delete policy %s, must be one of: %s, %s, %s", deletePolicy, DeletePolicyLatestSoft, DeletePolicyAllSoft, DeletePolicyMetadataDestroy))
+	}
+}
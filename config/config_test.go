@@ -81,6 +81,26 @@ var _ = Describe("Config", func() {
 				bvConfig := config.ParseConfig(nil)
 				Expect(bvConfig.Api.Address).To(Equal(config.DefaultApiListenAddress))
 				Expect(bvConfig.Log.Level).To(Equal(config.DefaultLogLevel))
+				Expect(bvConfig.Vault.DeletePolicy).To(Equal(config.DeletePolicyAllSoft))
+				Expect(bvConfig.Store.Backend).To(Equal(config.StoreBackendVault))
+				Expect(bvConfig.Uaa.Scopes).To(Equal(config.ScopeConfiguration{
+					Read:   []string{config.DefaultScope},
+					Write:  []string{config.DefaultScope},
+					Delete: []string{config.DefaultScope},
+				}))
+				Expect(bvConfig.Oidc.Scopes.Write).To(Equal([]string{config.DefaultScope}))
+			})
+		})
+		Context("a config with scopes", func() {
+			It("replaces the default scopes of the kinds that are configured", func() {
+				workingDirectory, _ := os.Getwd()
+				scopesConfigPath := filepath.Join(workingDirectory, "configfakes/scopes-config.yml")
+				bvConfig := config.ParseConfig(&scopesConfigPath)
+				Expect(bvConfig.Uaa.Scopes.Read).To(Equal([]string{"config_server.read", config.DefaultScope}))
+				Expect(bvConfig.Uaa.Scopes.Write).To(Equal([]string{config.DefaultScope}))
+				Expect(bvConfig.Uaa.Scopes.Delete).To(BeEmpty())
+				Expect(bvConfig.Oidc.Scopes.Read).To(Equal([]string{config.DefaultScope}))
+				Expect(bvConfig.Oidc.Scopes.Delete).To(Equal([]string{config.DefaultScope}))
 			})
 		})
 		Context("a non-existent file is specified", func() {
@@ -98,4 +118,16 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Describe("delete policy validation", func() {
+		It("accepts the known policies and an empty one", func() {
+			for _, deletePolicy := range []string{"", config.DeletePolicyLatestSoft, config.DeletePolicyAllSoft, config.DeletePolicyMetadataDestroy} {
+				Expect(config.ValidateDeletePolicy(deletePolicy)).To(Succeed())
+			}
+		})
+
+		It("rejects unknown policies", func() {
+			Expect(config.ValidateDeletePolicy("shred")).To(MatchError(ContainSubstring("unknown delete policy shred")))
+		})
+	})
+
 })
@@ -3,7 +3,19 @@ path "config-server/data/*" {
 }
 
 path "config-server/metadata/*" {
-  capabilities = ["read"]
+  capabilities = ["read", "list"]
+}
+
+path "config-server/delete/*" {
+  capabilities = ["update"]
+}
+
+path "config-server/undelete/*" {
+  capabilities = ["update"]
+}
+
+path "config-server/destroy/*" {
+  capabilities = ["update"]
 }
 
 path "kv1/*" {
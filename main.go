@@ -3,16 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/cli"
 	"github.com/cloudfoundry-community/bosh-vault/config"
 	"github.com/cloudfoundry-community/bosh-vault/logger"
 	"github.com/cloudfoundry-community/bosh-vault/server"
 	"github.com/cloudfoundry-community/bosh-vault/version"
 	"os"
+	"strings"
 )
 
 func main() {
 	showVersionAndExit := flag.Bool("version", false, "display version and exit")
 	configPath := flag.String("config", "", "path to the configuration file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [command]\n\ncommands: %s\n\nflags:\n", os.Args[0], strings.Join(cli.Commands, ", "))
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if *showVersionAndExit {
@@ -31,5 +37,15 @@ func main() {
 	logger.Initialize(bvConfig)
 	logger.Log.Infof("I am bosh-vault version %s", version.Version)
 
+	// any remaining arguments are a command to run against the store instead of starting the api server
+	if flag.NArg() > 0 {
+		err := cli.Run(bvConfig, flag.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	server.ListenAndServe(bvConfig)
 }
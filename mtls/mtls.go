@@ -0,0 +1,89 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/labstack/echo"
+	"io/ioutil"
+	"net/http"
+)
+
+// Authenticates clients by the certificate they presented, the TLS handshake already verified it with the client CA
+type Mtls struct {
+	Config config.MtlsConfiguration
+}
+
+func NewMtls(mtlsConfig config.MtlsConfiguration) (*Mtls, error) {
+	switch mtlsConfig.Identity {
+	case config.MtlsIdentityCommonName, config.MtlsIdentityDnsName, config.MtlsIdentityUri, config.MtlsIdentityEmail:
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown mtls identity %s, must be one of: %s, %s, %s, %s", mtlsConfig.Identity, config.MtlsIdentityCommonName, config.MtlsIdentityDnsName, config.MtlsIdentityUri, config.MtlsIdentityEmail))
+	}
+	return &Mtls{
+		Config: mtlsConfig,
+	}, nil
+}
+
+// The pool of the client CA bundle, every certificate in it is trusted
+func ClientCaPool(clientCa string) (*x509.CertPool, error) {
+	if clientCa == "" {
+		return nil, errors.New("the mtls provider needs a client CA")
+	}
+	certs, err := ioutil.ReadFile(clientCa)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if ok := pool.AppendCertsFromPEM(certs); !ok {
+		return nil, errors.New(fmt.Sprintf("no certificates found in the client CA %s", clientCa))
+	}
+	return pool, nil
+}
+
+func (m *Mtls) Name() string {
+	return config.AuthProviderMtls
+}
+
+// Only verified certificates count, a certificate the handshake didn't verify is the same as none
+func (m *Mtls) Authenticate(ctx echo.Context) (auth.Identity, error) {
+	req := ctx.Request()
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return auth.Identity{}, auth.ErrNoCredentials
+	}
+
+	certificate := req.TLS.VerifiedChains[0][0]
+	id := m.identity(certificate)
+	if id == "" {
+		errorText := fmt.Sprintf("client certificate %s has no %s to identify it", certificate.Subject, m.Config.Identity)
+		logger.Log.Error(errorText)
+		return auth.Identity{}, echo.NewHTTPError(http.StatusUnauthorized, errorText)
+	}
+	return auth.Identity{
+		Id:       id,
+		Provider: m.Name(),
+	}, nil
+}
+
+func (m *Mtls) identity(certificate *x509.Certificate) string {
+	switch m.Config.Identity {
+	case config.MtlsIdentityDnsName:
+		if len(certificate.DNSNames) > 0 {
+			return certificate.DNSNames[0]
+		}
+	case config.MtlsIdentityUri:
+		if len(certificate.URIs) > 0 {
+			return certificate.URIs[0].String()
+		}
+	case config.MtlsIdentityEmail:
+		if len(certificate.EmailAddresses) > 0 {
+			return certificate.EmailAddresses[0]
+		}
+	default:
+		return certificate.Subject.CommonName
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package mtls_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/mtls"
+	"github.com/labstack/echo"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mtls", func() {
+	certificate := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "director"},
+		DNSNames:       []string{"director.bosh", "director.internal"},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "bosh", Path: "/director"}},
+		EmailAddresses: []string{"director@bosh"},
+	}
+
+	authenticate := func(identity string, connection *tls.ConnectionState) (auth.Identity, error) {
+		provider, err := mtls.NewMtls(config.MtlsConfiguration{Identity: identity})
+		Expect(err).ToNot(HaveOccurred())
+		req := httptest.NewRequest(http.MethodGet, "/v1/data", nil)
+		req.TLS = connection
+		return provider.Authenticate(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	verified := func(certificate *x509.Certificate) *tls.ConnectionState {
+		return &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{certificate},
+			VerifiedChains:   [][]*x509.Certificate{{certificate}},
+		}
+	}
+
+	It("identifies clients by the configured part of their certificate", func() {
+		for identity, expected := range map[string]string{
+			config.MtlsIdentityCommonName: "director",
+			config.MtlsIdentityDnsName:    "director.bosh",
+			config.MtlsIdentityUri:        "spiffe://bosh/director",
+			config.MtlsIdentityEmail:      "director@bosh",
+		} {
+			id, err := authenticate(identity, verified(certificate))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(auth.Identity{Id: expected, Provider: config.AuthProviderMtls}))
+		}
+	})
+
+	It("rejects certificates without the part that identifies them", func() {
+		_, err := authenticate(config.MtlsIdentityUri, verified(&x509.Certificate{Subject: pkix.Name{CommonName: "director"}}))
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("leaves requests without a verified certificate to other providers", func() {
+		_, err := authenticate(config.MtlsIdentityCommonName, nil)
+		Expect(err).To(Equal(auth.ErrNoCredentials))
+
+		_, err = authenticate(config.MtlsIdentityCommonName, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}})
+		Expect(err).To(Equal(auth.ErrNoCredentials))
+	})
+
+	It("only knows the supported identities", func() {
+		_, err := mtls.NewMtls(config.MtlsConfiguration{Identity: "serialnumber"})
+		Expect(err).To(HaveOccurred())
+	})
+})
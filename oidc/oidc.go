@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/labstack/echo"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// The parts of the discovery document needed to check tokens
+// @see: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type DiscoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JwksUri string `json:"jwks_uri"`
+}
+
+type Oidc struct {
+	Config   config.OidcConfiguration
+	Keys     *auth.KeySet
+	verifier *auth.JwtVerifier
+}
+
+// Discovers the issuer's signing keys and fetches them, tokens have to be issued by the issuer for the audience
+func NewOidc(oidcConfig config.OidcConfiguration) (*Oidc, error) {
+	if oidcConfig.Issuer == "" || oidcConfig.Audience == "" {
+		return nil, errors.New("the oidc provider needs an issuer and an audience")
+	}
+
+	httpClient, err := auth.NewHttpClient(oidcConfig.Ca, oidcConfig.SkipVerify, oidcConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery, err := discover(httpClient, oidcConfig.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := auth.NewKeySet(discovery.JwksUri, httpClient)
+	err = keys.Refresh()
+	if err != nil {
+		return nil, err
+	}
+	keys.RefreshEvery(time.Duration(oidcConfig.KeyRefreshInterval) * time.Second)
+
+	return &Oidc{
+		Config: oidcConfig,
+		Keys:   keys,
+		verifier: &auth.JwtVerifier{
+			Keys:     keys,
+			Issuer:   discovery.Issuer,
+			Audience: oidcConfig.Audience,
+		},
+	}, nil
+}
+
+func discover(httpClient *http.Client, issuer string) (DiscoveryDocument, error) {
+	var discovery DiscoveryDocument
+
+	discoveryUrl := strings.TrimRight(issuer, "/") + DiscoveryPath
+	resp, err := httpClient.Get(discoveryUrl)
+	if err != nil {
+		return discovery, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return discovery, fmt.Errorf("received a status code %v when requesting %s", resp.Status, discoveryUrl)
+	}
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return discovery, err
+	}
+	err = json.Unmarshal(responseBody, &discovery)
+	if err != nil {
+		return discovery, err
+	}
+
+	// the issuer in the document is what tokens carry, it has to be the one that was configured
+	if discovery.Issuer != issuer {
+		return discovery, fmt.Errorf("discovery document of %s is for the issuer %s", issuer, discovery.Issuer)
+	}
+	if discovery.JwksUri == "" {
+		return discovery, fmt.Errorf("discovery document of %s has no jwks_uri", issuer)
+	}
+	logger.Log.Debugf("using the signing keys of %s from %s", issuer, discovery.JwksUri)
+	return discovery, nil
+}
+
+func (o *Oidc) Name() string {
+	return config.AuthProviderOidc
+}
+
+// Validates the token with the issuer's key it was signed with, then its issuer and audience
+func (o *Oidc) Authenticate(ctx echo.Context) (auth.Identity, error) {
+	token, ok := auth.BearerToken(ctx.Request())
+	if !ok {
+		return auth.Identity{}, auth.ErrNoCredentials
+	}
+	claims, err := o.verifier.Verify(token)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	return auth.TokenIdentity(o.Name(), claims), nil
+}
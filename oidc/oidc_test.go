@@ -0,0 +1,128 @@
+package oidc_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/oidc"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Oidc", func() {
+	var signingKey *rsa.PrivateKey
+	var fakeIssuer *httptest.Server
+	var discoveredIssuer string
+	var oidcConfig config.OidcConfiguration
+
+	sign := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(signingKey)
+		Expect(err).ToNot(HaveOccurred())
+		return signed
+	}
+
+	authenticate := func(provider *oidc.Oidc, token string) (auth.Identity, error) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/data", nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		return provider.Authenticate(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	BeforeEach(func() {
+		var err error
+		signingKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(oidc.DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(oidc.DiscoveryDocument{
+				Issuer:  discoveredIssuer,
+				JwksUri: fakeIssuer.URL + "/keys",
+			})
+		})
+		mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(auth.Jwks{Keys: []auth.Jwk{{
+				Kid: "key-1",
+				Alg: "RS256",
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+			}}})
+		})
+		fakeIssuer = httptest.NewServer(mux)
+		discoveredIssuer = fakeIssuer.URL
+
+		oidcConfig = config.ParseConfig(nil).Oidc
+		oidcConfig.Issuer = fakeIssuer.URL
+		oidcConfig.Audience = "bosh-vault"
+	})
+
+	AfterEach(func() {
+		fakeIssuer.Close()
+	})
+
+	It("authenticates tokens of the issuer for the audience", func() {
+		provider, err := oidc.NewOidc(oidcConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		identity, err := authenticate(provider, sign(jwt.MapClaims{
+			"iss":   fakeIssuer.URL,
+			"sub":   "director",
+			"aud":   "bosh-vault",
+			"scope": "openid bosh-vault.read",
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identity).To(Equal(auth.Identity{
+			Id:       "director",
+			Scopes:   []string{"openid", "bosh-vault.read"},
+			Provider: config.AuthProviderOidc,
+		}))
+
+		_, err = authenticate(provider, sign(jwt.MapClaims{"iss": fakeIssuer.URL, "sub": "director", "aud": []interface{}{"bosh-vault"}}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects tokens of other issuers or audiences", func() {
+		provider, err := oidc.NewOidc(oidcConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = authenticate(provider, sign(jwt.MapClaims{"iss": "https://other.example.com", "sub": "director", "aud": "bosh-vault"}))
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusUnauthorized))
+		_, err = authenticate(provider, sign(jwt.MapClaims{"iss": fakeIssuer.URL, "sub": "director", "aud": "other"}))
+		Expect(err.(*echo.HTTPError).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("leaves requests without a token to other providers", func() {
+		provider, err := oidc.NewOidc(oidcConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = authenticate(provider, "")
+		Expect(err).To(Equal(auth.ErrNoCredentials))
+	})
+
+	It("refuses a discovery document for another issuer", func() {
+		discoveredIssuer = "https://other.example.com"
+		_, err := oidc.NewOidc(oidcConfig)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("needs an issuer and an audience", func() {
+		oidcConfig.Audience = ""
+		_, err := oidc.NewOidc(oidcConfig)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	"strings"
+)
+
+// Returned for operations the client's policy doesn't allow
+type DeniedError struct {
+	Client    string
+	Operation string
+	Name      string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s is not allowed to %s %s", e.Client, e.Operation, e.Name)
+}
+
+// Clients are only unique per auth provider, a certificate identity can be the same as a UAA client id
+type client struct {
+	provider string
+	id       string
+}
+
+func IsDenied(err error) bool {
+	_, denied := err.(*DeniedError)
+	return denied
+}
+
+// The paths a single client may use
+type Policy struct {
+	// the client qualified by its provider, like mtls:director-a
+	Client string
+	Paths  []config.PolicyPath
+}
+
+type Policies struct {
+	byClient map[client][]config.PolicyPath
+}
+
+func NewPolicies(policiesConfig []config.PolicyConfiguration) (*Policies, error) {
+	policies := &Policies{
+		byClient: map[client][]config.PolicyPath{},
+	}
+	for _, policyConfig := range policiesConfig {
+		if len(policyConfig.Clients) == 0 {
+			return nil, errors.New("every policy needs at least one client")
+		}
+		provider := policyConfig.Provider
+		switch provider {
+		case "":
+			provider = config.AuthProviderUaa
+		case config.AuthProviderUaa, config.AuthProviderOidc, config.AuthProviderMtls:
+		default:
+			return nil, errors.New(fmt.Sprintf("unknown provider %s for the policy of %s, must be one of: %s, %s, %s", provider, strings.Join(policyConfig.Clients, ", "), config.AuthProviderUaa, config.AuthProviderOidc, config.AuthProviderMtls))
+		}
+		for _, path := range policyConfig.Paths {
+			for _, operation := range path.Operations {
+				switch operation {
+				case config.PolicyOperationRead, config.PolicyOperationWrite, config.PolicyOperationDelete, config.PolicyOperationSign:
+				default:
+					return nil, errors.New(fmt.Sprintf("unknown operation %s for %s, must be one of: %s, %s, %s, %s", operation, path.Prefix, config.PolicyOperationRead, config.PolicyOperationWrite, config.PolicyOperationDelete, config.PolicyOperationSign))
+				}
+			}
+		}
+		// a client listed in several policies gets the paths of all of them
+		for _, id := range policyConfig.Clients {
+			key := client{provider: provider, id: id}
+			policies.byClient[key] = append(policies.byClient[key], policyConfig.Paths...)
+		}
+	}
+	return policies, nil
+}
+
+// The policy of the client authenticated by the provider, a client without one gets an empty policy that allows nothing
+func (p *Policies) For(provider string, id string) Policy {
+	return Policy{
+		Client: fmt.Sprintf("%s:%s", provider, id),
+		Paths:  p.byClient[client{provider: provider, id: id}],
+	}
+}
+
+// Whether the operation is allowed on the credential, prefixes match whole path segments so /director won't match
+// /director-2/password. Names with relative segments are never allowed, /director/../other would match /director.
+func (p Policy) Allows(operation string, name string) bool {
+	if types.HasRelativeSegments(name) {
+		return false
+	}
+	name = "/" + strings.Trim(name, "/")
+	for _, path := range p.Paths {
+		prefix := "/" + strings.Trim(path.Prefix, "/")
+		if prefix != "/" && name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			continue
+		}
+		for _, allowed := range path.Operations {
+			if allowed == operation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p Policy) Authorize(operation string, name string) error {
+	if !p.Allows(operation, name) {
+		return &DeniedError{
+			Client:    p.Client,
+			Operation: operation,
+			Name:      name,
+		}
+	}
+	return nil
+}
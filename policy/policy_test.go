@@ -0,0 +1,149 @@
+package policy_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/policy"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var policies *policy.Policies
+
+	BeforeEach(func() {
+		var err error
+		policies, err = policy.NewPolicies([]config.PolicyConfiguration{
+			{
+				Clients: []string{"director"},
+				Paths: []config.PolicyPath{
+					{Prefix: "/director/", Operations: []string{"read", "write"}},
+					{Prefix: "/root-ca", Operations: []string{"sign"}},
+				},
+			},
+			{
+				Clients: []string{"admin"},
+				Paths: []config.PolicyPath{
+					{Prefix: "/", Operations: []string{"read", "write", "delete"}},
+				},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("matches prefixes by whole path segments", func() {
+		director := policies.For("uaa", "director")
+		Expect(director.Allows("read", "/director/deployment/password")).To(BeTrue())
+		Expect(director.Allows("read", "director")).To(BeTrue())
+		Expect(director.Allows("read", "/director-2/password")).To(BeFalse())
+		Expect(director.Allows("delete", "/director/deployment/password")).To(BeFalse())
+		Expect(director.Allows("read", "/director/../other/password")).To(BeFalse())
+		Expect(director.Allows("read", "/director/./password")).To(BeFalse())
+
+		Expect(policies.For("uaa", "admin").Allows("delete", "/anything/at/all")).To(BeTrue())
+		Expect(policies.For("uaa", "someone-else").Allows("read", "/director/password")).To(BeFalse())
+	})
+
+	It("only applies policies to clients of their provider", func() {
+		policies, err := policy.NewPolicies([]config.PolicyConfiguration{
+			{
+				Provider: "mtls",
+				Clients:  []string{"director"},
+				Paths:    []config.PolicyPath{{Prefix: "/director-certificate", Operations: []string{"read"}}},
+			},
+			{
+				Clients: []string{"director"},
+				Paths:   []config.PolicyPath{{Prefix: "/director-uaa", Operations: []string{"read"}}},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(policies.For("mtls", "director").Allows("read", "/director-certificate/password")).To(BeTrue())
+		Expect(policies.For("mtls", "director").Allows("read", "/director-uaa/password")).To(BeFalse())
+		Expect(policies.For("uaa", "director").Allows("read", "/director-uaa/password")).To(BeTrue())
+		Expect(policies.For("oidc", "director").Allows("read", "/director-uaa/password")).To(BeFalse())
+		Expect(policies.For("oidc", "director").Client).To(Equal("oidc:director"))
+
+		_, err = policy.NewPolicies([]config.PolicyConfiguration{
+			{Provider: "ldap", Clients: []string{"director"}},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown operations", func() {
+		_, err := policy.NewPolicies([]config.PolicyConfiguration{
+			{Clients: []string{"director"}, Paths: []config.PolicyPath{{Prefix: "/", Operations: []string{"everything"}}}},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("Store", func() {
+		var secretStore *store.MemoryStore
+		var directorStore *policy.Store
+
+		BeforeEach(func() {
+			secretStore = store.NewMemoryStore()
+			directorStore = policy.NewStore(secretStore, policies.For("uaa", "director"))
+		})
+
+		It("checks every operation against the policy", func() {
+			id, err := directorStore.Set("/director/password", map[string]interface{}{"value": "a"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = directorStore.GetById(id)
+			Expect(err).ToNot(HaveOccurred())
+
+			otherId, err := secretStore.Set("/other/password", map[string]interface{}{"value": "b"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = directorStore.GetById(otherId)
+			Expect(policy.IsDenied(err)).To(BeTrue())
+			_, err = directorStore.Set("/other/password", map[string]interface{}{"value": "c"})
+			Expect(policy.IsDenied(err)).To(BeTrue())
+			Expect(directorStore.Exists("/other/password")).To(BeFalse())
+
+			err = directorStore.DeleteByName("/director/password")
+			Expect(policy.IsDenied(err)).To(BeTrue())
+			Expect(secretStore.Exists("/director/password")).To(BeTrue())
+		})
+
+		It("treats generation parameters like their credential", func() {
+			_, err := directorStore.Set(types.GenerationRequestName("/director/password"), map[string]interface{}{"request": "{}"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = directorStore.Set(types.GenerationRequestName("/other/password"), map[string]interface{}{"request": "{}"})
+			Expect(policy.IsDenied(err)).To(BeTrue())
+		})
+
+		It("treats the emulated KV1 history like its credential", func() {
+			Expect(types.ReservedCredentialName("/bosh-vault/kv1-history/metadata/director/password")).To(Equal("/director/password"))
+			Expect(types.ReservedCredentialName("/bosh-vault/kv1-history/versions/director/password/2")).To(Equal("/director/password"))
+			_, err := directorStore.Set("/bosh-vault/kv1-history/versions/other/password/1", map[string]interface{}{"value": "b"})
+			Expect(policy.IsDenied(err)).To(BeTrue())
+		})
+
+		It("only lists readable credentials", func() {
+			_, _ = secretStore.Set("/director/password", map[string]interface{}{"value": "a"})
+			_, _ = secretStore.Set("/other/password", map[string]interface{}{"value": "b"})
+			_, _ = secretStore.Set("/root-ca", map[string]interface{}{"certificate": "c"})
+
+			names, err := directorStore.List("/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"/director/password"}))
+		})
+
+		It("lets CAs be signed with but not read", func() {
+			_, _ = secretStore.Set("/root-ca", map[string]interface{}{"certificate": "c"})
+
+			_, err := directorStore.GetLatestByName("/root-ca")
+			Expect(policy.IsDenied(err)).To(BeTrue())
+
+			caStore, err := directorStore.AuthorizeCa("/root-ca")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = caStore.GetLatestByName("/root-ca")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = directorStore.AuthorizeCa("/other/ca")
+			Expect(policy.IsDenied(err)).To(BeTrue())
+		})
+	})
+})
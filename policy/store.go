@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+)
+
+// Wraps the store for a single client and checks every operation against the client's policy. Listing only returns the
+// credentials the client may read and bookkeeping like generation parameters is treated like the credential it belongs to.
+type Store struct {
+	secret.Store
+	Policy Policy
+}
+
+func NewStore(secretStore secret.Store, policy Policy) *Store {
+	return &Store{
+		Store:  secretStore,
+		Policy: policy,
+	}
+}
+
+// Signing with a CA needs either the sign or the read operation on it, the CA is then read from the unrestricted store
+func (s *Store) AuthorizeCa(caName string) (secret.Store, error) {
+	if s.Policy.Allows(config.PolicyOperationSign, credentialName(caName)) || s.Policy.Allows(config.PolicyOperationRead, credentialName(caName)) {
+		return s.Store, nil
+	}
+	return nil, s.authorize(config.PolicyOperationSign, caName)
+}
+
+func (s *Store) Exists(name string) bool {
+	return s.authorize(config.PolicyOperationRead, name) == nil && s.Store.Exists(name)
+}
+
+func (s *Store) GetLatestByName(name string) (secret.Secret, error) {
+	err := s.authorize(config.PolicyOperationRead, name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	return s.Store.GetLatestByName(name)
+}
+
+func (s *Store) GetByName(name string) ([]secret.Secret, error) {
+	err := s.authorize(config.PolicyOperationRead, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.GetByName(name)
+}
+
+func (s *Store) GetById(id string) (secret.Secret, error) {
+	decodedId, err := store.DecodeId(id)
+	if err == nil {
+		err = s.authorize(config.PolicyOperationRead, decodedId.Name)
+		if err != nil {
+			return secret.Secret{}, err
+		}
+	}
+	// ids that can't be decoded are left for the store to reject
+	return s.Store.GetById(id)
+}
+
+func (s *Store) Set(name string, value interface{}) (string, error) {
+	err := s.authorize(config.PolicyOperationWrite, name)
+	if err != nil {
+		return "", err
+	}
+	return s.Store.Set(name, value)
+}
+
+func (s *Store) DeleteByName(name string) error {
+	err := s.authorize(config.PolicyOperationDelete, name)
+	if err != nil {
+		return err
+	}
+	return s.Store.DeleteByName(name)
+}
+
+func (s *Store) GetVersions(name string) ([]secret.Version, error) {
+	err := s.authorize(config.PolicyOperationRead, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.GetVersions(name)
+}
+
+func (s *Store) Undelete(name string, versions []int) error {
+	err := s.authorize(config.PolicyOperationWrite, name)
+	if err != nil {
+		return err
+	}
+	return s.Store.Undelete(name, versions)
+}
+
+func (s *Store) Destroy(name string, versions []int) error {
+	err := s.authorize(config.PolicyOperationDelete, name)
+	if err != nil {
+		return err
+	}
+	return s.Store.Destroy(name, versions)
+}
+
+func (s *Store) List(prefix string) ([]string, error) {
+	names, err := s.Store.List(prefix)
+	if err != nil {
+		return names, err
+	}
+	readable := make([]string, 0, len(names))
+	for _, name := range names {
+		if s.Policy.Allows(config.PolicyOperationRead, credentialName(name)) {
+			readable = append(readable, name)
+		}
+	}
+	return readable, nil
+}
+
+func (s *Store) authorize(operation string, name string) error {
+	return s.Policy.Authorize(operation, credentialName(name))
+}
+
+// Stored generation parameters and transitional versions belong to a credential, any other name is a credential itself
+func credentialName(name string) string {
+	return types.ReservedCredentialName(name)
+}
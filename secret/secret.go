@@ -6,6 +6,15 @@ type Secret struct {
 	Id    string      `json:"id"`
 }
 
+// Metadata of a single version of a secret, deleted and destroyed versions are included
+type Version struct {
+	Id           string `json:"id"`
+	Version      int    `json:"version"`
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time,omitempty"`
+	Destroyed    bool   `json:"destroyed"`
+}
+
 type Store interface {
 	Exists(name string) bool
 	GetLatestByName(name string) (Secret, error)
@@ -13,5 +22,14 @@ type Store interface {
 	GetById(id string) (Secret, error)
 	Set(name string, value interface{}) (string, error)
 	DeleteByName(name string) error
+	GetVersions(name string) ([]Version, error)
+	Undelete(name string, versions []int) error
+	Destroy(name string, versions []int) error
+	// names of every secret under the prefix, including nested paths
+	List(prefix string) ([]string, error)
 	Healthy() bool
+	// details about the backend reported by the health endpoint
+	Status() map[string]interface{}
+	// stops background work like token renewal, called on shutdown
+	Close()
 }
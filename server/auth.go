@@ -0,0 +1,67 @@
+package server
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/mtls"
+	"github.com/cloudfoundry-community/bosh-vault/oidc"
+	"github.com/cloudfoundry-community/bosh-vault/uaa"
+)
+
+// The configured providers with their signing keys fetched, bosh-vault can't authenticate anything without them
+func authProviders(bvConfig config.Configuration) []auth.Provider {
+	if len(bvConfig.Auth.Providers) == 0 {
+		logger.Log.Fatal("no auth providers configured")
+	}
+
+	providers := make([]auth.Provider, 0, len(bvConfig.Auth.Providers))
+	for _, providerName := range bvConfig.Auth.Providers {
+		switch providerName {
+		case config.AuthProviderUaa:
+			uaaClient := uaa.GetUaa(bvConfig)
+			err := uaaClient.Keys.Refresh()
+			if err != nil {
+				// connection lost with UAA
+				logger.Log.Fatal(err)
+			}
+			providers = append(providers, uaaClient)
+		case config.AuthProviderOidc:
+			oidcClient, err := oidc.NewOidc(bvConfig.Oidc)
+			if err != nil {
+				logger.Log.Fatal(err)
+			}
+			providers = append(providers, oidcClient)
+		case config.AuthProviderMtls:
+			mtlsClient, err := mtls.NewMtls(bvConfig.Mtls)
+			if err != nil {
+				logger.Log.Fatal(err)
+			}
+			providers = append(providers, mtlsClient)
+		default:
+			logger.Log.Fatalf("unknown auth provider %s, must be one of: %s, %s, %s", providerName, config.AuthProviderUaa, config.AuthProviderOidc, config.AuthProviderMtls)
+		}
+	}
+	return providers
+}
+
+func providerScopes(bvConfig config.Configuration, providerName string) config.ScopeConfiguration {
+	switch providerName {
+	case config.AuthProviderOidc:
+		return bvConfig.Oidc.Scopes
+	case config.AuthProviderMtls:
+		// certificates don't carry scopes, policies limit what their clients may do
+		return config.ScopeConfiguration{}
+	default:
+		return bvConfig.Uaa.Scopes
+	}
+}
+
+func authProviderConfigured(bvConfig config.Configuration, providerName string) bool {
+	for _, configured := range bvConfig.Auth.Providers {
+		if configured == providerName {
+			return true
+		}
+	}
+	return false
+}
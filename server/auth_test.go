@@ -0,0 +1,242 @@
+package server_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/server"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorization", func() {
+	var signingKey *rsa.PrivateKey
+	var fakeUaa *httptest.Server
+	var bvConfig config.Configuration
+	var api *echo.Echo
+
+	sign := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(signingKey)
+		Expect(err).ToNot(HaveOccurred())
+		return signed
+	}
+
+	tokenFor := func(client string, scopes ...string) string {
+		return sign(jwt.MapClaims{
+			"client_id": client,
+			"aud":       []interface{}{"config_server"},
+			"scope":     scopes,
+		})
+	}
+
+	token := func(scopes ...string) string {
+		return tokenFor("director", scopes...)
+	}
+
+	request := func(method string, uri string, body string, token string) int {
+		req := httptest.NewRequest(method, uri, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	BeforeEach(func() {
+		var err error
+		signingKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		publicKey, err := x509.MarshalPKIXPublicKey(&signingKey.PublicKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeUaa = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// doubles as an oidc issuer with the same keys
+			if r.URL.Path == "/oidc/.well-known/openid-configuration" {
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"issuer":   fakeUaa.URL + "/oidc",
+					"jwks_uri": fakeUaa.URL + "/token_keys",
+				})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{
+					{
+						"kid":   "key-1",
+						"alg":   "RS256",
+						"kty":   "RSA",
+						"value": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+					},
+				},
+			})
+		}))
+
+		bvConfig = config.ParseConfig(nil)
+		bvConfig.Uaa.Address = fakeUaa.URL
+		bvConfig.Uaa.Scopes = config.ScopeConfiguration{
+			Read:   []string{"config_server.read", "config_server.admin"},
+			Write:  []string{"config_server.write", "config_server.admin"},
+			Delete: []string{"config_server.delete", "config_server.admin"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		api = server.NewServer(bvConfig, store.NewMemoryStore())
+	})
+
+	AfterEach(func() {
+		fakeUaa.Close()
+	})
+
+	It("needs a token for everything but the health endpoint", func() {
+		Expect(request(http.MethodGet, "/v1/health", "", "")).To(Equal(http.StatusOK))
+		Expect(request(http.MethodGet, "/v1/data?name=/password", "", "")).To(Equal(http.StatusBadRequest))
+	})
+
+	It("checks the scopes configured for reading, writing, and deleting", func() {
+		reader := token("config_server.read")
+		writer := token("config_server.write")
+		admin := token("config_server.admin")
+		body := `{"name": "/password", "type": "password"}`
+
+		Expect(request(http.MethodPost, "/v1/data", body, reader)).To(Equal(http.StatusForbidden))
+		Expect(request(http.MethodPost, "/v1/data", body, writer)).To(Equal(http.StatusCreated))
+
+		Expect(request(http.MethodGet, "/v1/data?name=/password", "", writer)).To(Equal(http.StatusForbidden))
+		Expect(request(http.MethodGet, "/v1/data?name=/password", "", reader)).To(Equal(http.StatusOK))
+		Expect(request(http.MethodGet, "/v1/versions?name=/password", "", reader)).To(Equal(http.StatusOK))
+
+		Expect(request(http.MethodDelete, "/v1/data?name=/password", "", writer)).To(Equal(http.StatusForbidden))
+		Expect(request(http.MethodPost, "/v1/destroy", `{"name": "/password", "versions": [1]}`, reader)).To(Equal(http.StatusForbidden))
+		Expect(request(http.MethodDelete, "/v1/data?name=/password", "", admin)).To(Equal(http.StatusNoContent))
+	})
+
+	Context("with policies", func() {
+		BeforeEach(func() {
+			bvConfig.Uaa.Scopes = config.ScopeConfiguration{}
+			bvConfig.Policies = []config.PolicyConfiguration{
+				{
+					Clients: []string{"director-a"},
+					Paths: []config.PolicyPath{
+						{Prefix: "/director-a", Operations: []string{"read", "write", "delete"}},
+						{Prefix: "/shared/ca", Operations: []string{"sign"}},
+					},
+				},
+				{
+					Clients: []string{"director-b", "ca-admin"},
+					Paths: []config.PolicyPath{
+						{Prefix: "/director-b", Operations: []string{"read", "write"}},
+					},
+				},
+				{
+					Clients: []string{"ca-admin"},
+					Paths: []config.PolicyPath{
+						{Prefix: "/shared", Operations: []string{"read", "write"}},
+					},
+				},
+			}
+		})
+
+		It("limits clients to their paths and operations", func() {
+			directorA := tokenFor("director-a")
+			directorB := tokenFor("director-b")
+			password := `{"name": "/director-a/deployment/password", "type": "password"}`
+
+			Expect(request(http.MethodPost, "/v1/data", password, directorB)).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodPost, "/v1/data", password, directorA)).To(Equal(http.StatusCreated))
+			Expect(request(http.MethodPost, "/v1/data", `{"name": "/director-ab/password", "type": "password"}`, directorA)).To(Equal(http.StatusForbidden))
+
+			Expect(request(http.MethodGet, "/v1/data?name=/director-a/deployment/password", "", directorB)).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodGet, "/v1/data?name=/director-a/deployment/password", "", directorA)).To(Equal(http.StatusOK))
+			Expect(request(http.MethodGet, "/v1/data?name=/director-a/deployment/password", "", tokenFor("unknown"))).To(Equal(http.StatusForbidden))
+
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/director-b/value", "type": "value", "value": "b"}`, directorB)).To(Equal(http.StatusOK))
+			Expect(request(http.MethodDelete, "/v1/data?name=/director-b/value", "", directorB)).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodDelete, "/v1/data?name=/director-a/deployment/password", "", directorA)).To(Equal(http.StatusNoContent))
+		})
+
+		It("only lists the credentials a client may read", func() {
+			Expect(request(http.MethodPost, "/v1/data", `{"name": "/director-a/password", "type": "password"}`, tokenFor("director-a"))).To(Equal(http.StatusCreated))
+			Expect(request(http.MethodPost, "/v1/data", `{"name": "/director-b/password", "type": "password"}`, tokenFor("director-b"))).To(Equal(http.StatusCreated))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/data?path=/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "bearer "+tokenFor("director-b"))
+			recorder := httptest.NewRecorder()
+			api.ServeHTTP(recorder, req)
+			Expect(recorder.Body.String()).To(ContainSubstring(`"total":1`))
+			Expect(recorder.Body.String()).To(ContainSubstring("/director-b/password"))
+		})
+
+		It("only signs with CAs the client may sign with", func() {
+			ca := `{"name": "/%s", "type": "certificate", "parameters": {"is_ca": true, "common_name": "ca"}}`
+			Expect(request(http.MethodPost, "/v1/data", fmt.Sprintf(ca, "shared/ca"), tokenFor("ca-admin"))).To(Equal(http.StatusCreated))
+			Expect(request(http.MethodPost, "/v1/data", fmt.Sprintf(ca, "director-b/ca"), tokenFor("director-b"))).To(Equal(http.StatusCreated))
+
+			certificate := `{"name": "/director-a/certificate", "type": "certificate", "parameters": {"ca": "%s", "common_name": "director-a"}}`
+			Expect(request(http.MethodPost, "/v1/data", fmt.Sprintf(certificate, "/director-b/ca"), tokenFor("director-a"))).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodPost, "/v1/data", fmt.Sprintf(certificate, "/shared/ca"), tokenFor("director-a"))).To(Equal(http.StatusCreated))
+			Expect(request(http.MethodGet, "/v1/data?name=/shared/ca", "", tokenFor("director-a"))).To(Equal(http.StatusForbidden))
+		})
+	})
+	Context("with an oidc issuer as well", func() {
+		BeforeEach(func() {
+			bvConfig.Auth.Providers = []string{config.AuthProviderUaa, config.AuthProviderOidc}
+			bvConfig.Oidc.Issuer = fakeUaa.URL + "/oidc"
+			bvConfig.Oidc.Audience = "bosh-vault"
+			bvConfig.Oidc.Scopes = config.ScopeConfiguration{
+				Read: []string{"bosh-vault.read"},
+			}
+		})
+
+		It("accepts tokens of either and checks the scopes of the one that issued it", func() {
+			oidcToken := func(scope string) string {
+				return sign(jwt.MapClaims{"iss": fakeUaa.URL + "/oidc", "sub": "director", "aud": "bosh-vault", "scope": scope})
+			}
+
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", oidcToken("bosh-vault.read"))).To(Equal(http.StatusNotFound))
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", oidcToken("config_server.read"))).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", token("config_server.read"))).To(Equal(http.StatusNotFound))
+
+			otherIssuer := sign(jwt.MapClaims{"iss": "https://other.example.com", "sub": "director", "aud": "bosh-vault", "scope": "bosh-vault.read"})
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", otherIssuer)).To(Equal(http.StatusUnauthorized))
+		})
+	})
+	Context("with client certificates as well", func() {
+		BeforeEach(func() {
+			bvConfig.Auth.Providers = []string{config.AuthProviderUaa, config.AuthProviderMtls}
+		})
+
+		It("accepts a verified certificate instead of a token", func() {
+			certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "director"}}
+			req := httptest.NewRequest(http.MethodGet, "/v1/data?name=/password", nil)
+			req.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{certificate},
+				VerifiedChains:   [][]*x509.Certificate{{certificate}},
+			}
+			recorder := httptest.NewRecorder()
+			api.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", token("config_server.write"))).To(Equal(http.StatusForbidden))
+			Expect(request(http.MethodGet, "/v1/data?name=/password", "", "")).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -1,13 +1,21 @@
 package server
 
 import (
+	stdcontext "context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/policy"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/store"
 	"github.com/cloudfoundry-community/bosh-vault/types"
 	"github.com/labstack/echo"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 )
 
 func healthCheckHandler(ctx echo.Context) error {
@@ -17,16 +25,122 @@ func healthCheckHandler(ctx echo.Context) error {
 		return ctx.JSON(http.StatusOK, &map[string]interface{}{
 			"status":      http.StatusOK,
 			"status_text": http.StatusText(http.StatusOK),
+			"store":       context.Store.Status(),
 		})
 	} else {
 		return ctx.JSON(http.StatusInternalServerError, &map[string]interface{}{
 			"status":      http.StatusInternalServerError,
-			"status_text": fmt.Sprintf("%s your backend store is unhealthy, has it been initialized and unsealed?", http.StatusText(http.StatusInternalServerError)),
+			"status_text": fmt.Sprintf("%s your backend store is unhealthy, has it been initialized and unsealed and is its token still valid?", http.StatusText(http.StatusInternalServerError)),
+			"store":       context.Store.Status(),
 		})
 	}
 
 }
 
+// Single value credentials (password, value, json) are stored nested under a "value" key, return those as the bare
+// value instead (a password is just a string, for example). Only a single level is unwrapped so json credentials that
+// have their own "value" key come back intact, string values are always unwrapped to support secrets written elsewhere.
+func unwrapSecretValue(value interface{}) interface{} {
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	wrapped, ok := valueMap["value"]
+	if !ok {
+		return value
+	}
+
+	if _, isString := wrapped.(string); isString || len(valueMap) == 1 {
+		return wrapped
+	}
+
+	return value
+}
+
+// GET /v1/data finds credentials either by exact name or by path prefix
+func dataGetHandler(ctx echo.Context) error {
+	if ctx.QueryParam("path") != "" {
+		return dataGetByPathHandler(ctx)
+	}
+	return dataGetByNameHandler(ctx)
+}
+
+func dataGetByPathHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	path := ctx.QueryParam("path")
+	context.Log.Debugf("request to GET %s?path=%s", dataUri, path)
+	err := checkName(context, path)
+	if err != nil {
+		return err
+	}
+
+	offset, err := nonNegativeQueryParam(ctx, "offset")
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return err
+	}
+	// a limit of 0 returns everything after the offset
+	limit, err := nonNegativeQueryParam(ctx, "limit")
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return err
+	}
+
+	names, err := context.Store.List(path)
+	if err != nil {
+		context.Log.Errorf("problem listing secrets under path: %s %s", path, err)
+		ctx.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem listing secrets under path: %s %s", path, err)))
+		return err
+	}
+
+	// stored generation parameters and transitional versions are bookkeeping, not credentials
+	credentials := make([]pathCredential, 0)
+	for _, name := range names {
+		if !types.IsReservedName(name) {
+			credentials = append(credentials, pathCredential{Name: name})
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].Name < credentials[j].Name
+	})
+
+	total := len(credentials)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	return ctx.JSON(http.StatusOK, pathResponse{
+		Credentials: credentials[offset:end],
+		Total:       total,
+	})
+}
+
+type pathCredential struct {
+	Name string `json:"name"`
+}
+
+type pathResponse struct {
+	Credentials []pathCredential `json:"credentials"`
+	Total       int              `json:"total"`
+}
+
+func nonNegativeQueryParam(ctx echo.Context, param string) (int, error) {
+	rawValue := ctx.QueryParam(param)
+	if rawValue == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(rawValue)
+	if err != nil || value < 0 {
+		return 0, errors.New(fmt.Sprintf("%s must be a non negative number", param))
+	}
+	return value, nil
+}
+
 func dataGetByNameHandler(ctx echo.Context) error {
 	context := ctx.(*BvContext)
 	name := ctx.QueryParam("name")
@@ -36,6 +150,14 @@ func dataGetByNameHandler(ctx echo.Context) error {
 		return errors.New("name query param not passed to data?name handler")
 	}
 	context.Log.Debugf("request to GET %s?name=%s", dataUri, name)
+	err := checkName(context, name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationRead, name)
+	if err != nil {
+		return err
+	}
 
 	secretResponses, err := context.Store.GetByName(name)
 	if err != nil {
@@ -43,16 +165,11 @@ func dataGetByNameHandler(ctx echo.Context) error {
 		return err
 	}
 
-	// Check to see if the value is a flat string value and don't nest it if that's the case (password type, for example)
-	// todo: look at checking for integer values too
 	responseData := make([]secret.Secret, 0)
 	for _, sr := range secretResponses {
-		// This will filter out secret versions that previously existed but are now deleted in Vault and this have no value
+		// stores only return live versions but a redirect Vault may still answer without a value
 		if sr.Value != nil {
-			valString, ok := sr.Value.(map[string]interface{})["value"].(string)
-			if ok {
-				sr.Value = valString
-			}
+			sr.Value = unwrapSecretValue(sr.Value)
 			responseData = append(responseData, sr)
 		}
 	}
@@ -73,6 +190,17 @@ func dataGetByIdHandler(ctx echo.Context) error {
 		return errors.New("id uri param not passed to data/:id handler")
 	}
 	context.Log.Debugf("request to %s/%s", dataUri, id)
+	// ids that can't be decoded aren't found either
+	if decodedId, err := store.DecodeId(id); err == nil {
+		err = checkName(context, decodedId.Name)
+		if err != nil {
+			return err
+		}
+		err = authorize(context, config.PolicyOperationRead, decodedId.Name)
+		if err != nil {
+			return err
+		}
+	}
 	vaultSecretResponse, err := context.Store.GetById(id)
 	if err != nil {
 		ctx.Error(echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("problem fetching secret by id: %s %s", id, err)))
@@ -81,10 +209,7 @@ func dataGetByIdHandler(ctx echo.Context) error {
 	// If this particular secret version has been deleted don't try to cast the value, allow it to be "null"
 	// Alternatively we could return a 404, this approach shows that at least at one point this ID was valid
 	if vaultSecretResponse.Value != nil {
-		valString, ok := vaultSecretResponse.Value.(map[string]interface{})["value"].(string)
-		if ok {
-			vaultSecretResponse.Value = valString
-		}
+		vaultSecretResponse.Value = unwrapSecretValue(vaultSecretResponse.Value)
 	}
 	return ctx.JSON(http.StatusOK, vaultSecretResponse)
 }
@@ -106,6 +231,22 @@ func dataPostHandler(ctx echo.Context) error {
 		return err
 	}
 
+	err = checkName(context, credentialRequest.CredentialName())
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationWrite, credentialRequest.CredentialName())
+	if err != nil {
+		return err
+	}
+	// the existing credential is returned in no-overwrite mode
+	if noOverrideMode {
+		err = authorize(context, config.PolicyOperationRead, credentialRequest.CredentialName())
+		if err != nil {
+			return err
+		}
+	}
+
 	if noOverrideMode && context.Store.Exists(credentialRequest.CredentialName()) {
 		latest, err := context.Store.GetLatestByName(credentialRequest.CredentialName())
 		if err != nil {
@@ -125,19 +266,301 @@ func dataPostHandler(ctx echo.Context) error {
 
 	context.Log.Debugf("attempting to generate %s", credentialType)
 
+	credentialResponse, err := generateAndStore(context, credentialRequest)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusCreated, &credentialResponse)
+}
+
+func regeneratePostHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	requestBody, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err))
+		return err
+	}
+
+	context.Log.Debugf("request: %s", requestBody)
+
+	var regenerateRequest types.RegenerateRequest
+	err = json.Unmarshal(requestBody, &regenerateRequest)
+	if err != nil || regenerateRequest.Name == "" {
+		context.Log.Error("request error: ", err)
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "regenerate requests must include a credential name"))
+		return errors.New("regenerate requests must include a credential name")
+	}
+
+	err = checkName(context, regenerateRequest.Name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationWrite, regenerateRequest.Name)
+	if err != nil {
+		return err
+	}
+
+	credentialRequest, err := types.GetGenerationRequest(context.Store, regenerateRequest.Name)
+	if err != nil {
+		context.Log.Error("request error: ", err)
+		ctx.Error(echo.NewHTTPError(http.StatusNotFound, err.Error()))
+		return err
+	}
+
+	// only CAs can be transitional, a regenerated CA is active unless explicitly asked for otherwise
+	certificateRequest, isCertificate := credentialRequest.(*types.CertificateRequest)
+	if isCertificate && certificateRequest.Parameters.IsCa {
+		certificateRequest.Parameters.Transitional = regenerateRequest.SetAsTransitional
+	} else if regenerateRequest.SetAsTransitional {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "only certificate authorities can be set as transitional"))
+		return errors.New("only certificate authorities can be set as transitional")
+	}
+
+	if !credentialRequest.Validate() {
+		context.Log.Errorf("stored generation parameters for %s are no longer valid", regenerateRequest.Name)
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("stored generation parameters for %s are no longer valid", regenerateRequest.Name)))
+		return errors.New("invalid stored generation parameters")
+	}
+
+	context.Log.Debugf("attempting to regenerate %s", regenerateRequest.Name)
+
+	credentialResponse, err := generateAndStore(context, credentialRequest)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, &credentialResponse)
+}
+
+func bulkRegeneratePostHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	requestBody, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err))
+		return err
+	}
+
+	context.Log.Debugf("request: %s", requestBody)
+
+	var bulkRegenerateRequest types.BulkRegenerateRequest
+	err = json.Unmarshal(requestBody, &bulkRegenerateRequest)
+	if err != nil || bulkRegenerateRequest.SignedBy == "" {
+		context.Log.Error("request error: ", err)
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "bulk regenerate requests must include the signed_by CA name"))
+		return errors.New("bulk regenerate requests must include the signed_by CA name")
+	}
+
+	// large stores should be regenerated with the bulk-regenerate command, a request only runs as long as the timeout
+	bulkRegenerateCtx, cancel := stdcontext.WithTimeout(ctx.Request().Context(), time.Duration(context.Config.Api.BulkRegenerateTimeout)*time.Second)
+	defer cancel()
+	bulkRegenerateResponse, err := types.BulkRegenerate(bulkRegenerateCtx, context.Store, bulkRegenerateRequest.SignedBy)
+	if err != nil {
+		context.Log.Error(err)
+		ctx.Error(echo.NewHTTPError(errorStatus(err, http.StatusInternalServerError), fmt.Sprintf("problem regenerating certificates signed by %s: %s", bulkRegenerateRequest.SignedBy, err)))
+		return err
+	}
+	if bulkRegenerateResponse.Incomplete {
+		context.Log.Errorf("stopped regenerating certificates signed by %s: %s", bulkRegenerateRequest.SignedBy, bulkRegenerateCtx.Err())
+	}
+
+	return ctx.JSON(http.StatusOK, &bulkRegenerateResponse)
+}
+
+func certificatesGetHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	context.Log.Debugf("request to GET %s", ctx.Request().RequestURI)
+
+	inventoryRequest := types.CertificateInventoryRequest{
+		SignedBy: ctx.QueryParam("signed_by"),
+	}
+	if expiresWithinDays := ctx.QueryParam("expires_within_days"); expiresWithinDays != "" {
+		days, err := strconv.Atoi(expiresWithinDays)
+		if err != nil {
+			ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "expires_within_days must be a number of days"))
+			return err
+		}
+		inventoryRequest.ExpiresWithinDays = days
+	}
+
+	inventoryResponse, err := types.CertificateInventory(context.Store, inventoryRequest)
+	if err != nil {
+		context.Log.Error(err)
+		ctx.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem listing certificates: %s", err)))
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, &inventoryResponse)
+}
+
+func versionsGetHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	name := ctx.QueryParam("name")
+	if name == "" {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "name query param not passed to versions?name handler"))
+		return errors.New("name query param not passed to versions?name handler")
+	}
+	context.Log.Debugf("request to GET %s?name=%s", versionsUri, name)
+	err := checkName(context, name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationRead, name)
+	if err != nil {
+		return err
+	}
+
+	versions, err := context.Store.GetVersions(name)
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("problem fetching versions by name: %s %s", name, err)))
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, struct {
+		Versions []secret.Version `json:"versions"`
+	}{
+		Versions: versions,
+	})
+}
+
+func undeletePostHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	return versionsOperation(context, "undelete", config.PolicyOperationWrite, context.Store.Undelete)
+}
+
+func destroyPostHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	return versionsOperation(context, "destroy", config.PolicyOperationDelete, context.Store.Destroy)
+}
+
+// Undelete and destroy take the same request, both respond with the versions of the secret after the operation
+func versionsOperation(context *BvContext, operation string, policyOperation string, apply func(name string, versions []int) error) error {
+	requestBody, err := ioutil.ReadAll(context.Request().Body)
+	if err != nil {
+		context.Error(echo.NewHTTPError(http.StatusBadRequest, err))
+		return err
+	}
+
+	context.Log.Debugf("request: %s", requestBody)
+
+	var versionsRequest types.VersionsRequest
+	err = json.Unmarshal(requestBody, &versionsRequest)
+	if err != nil || !versionsRequest.Validate() {
+		context.Log.Error("request error: ", err)
+		context.Error(echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s requests must include a name and a list of versions", operation)))
+		return errors.New(fmt.Sprintf("%s requests must include a name and a list of versions", operation))
+	}
+
+	err = checkName(context, versionsRequest.Name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, policyOperation, versionsRequest.Name)
+	if err != nil {
+		return err
+	}
+
+	// Exists is false when the latest version is deleted so check the version history instead
+	_, err = context.Store.GetVersions(versionsRequest.Name)
+	if err != nil {
+		context.Error(echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("problem fetching versions by name: %s %s", versionsRequest.Name, err)))
+		return err
+	}
+
+	err = apply(versionsRequest.Name, versionsRequest.Versions)
+	if err != nil {
+		context.Log.Errorf("problem with %s of %s versions %v: %s", operation, versionsRequest.Name, versionsRequest.Versions, err)
+		context.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem with %s of %s: %s", operation, versionsRequest.Name, err)))
+		return err
+	}
+	context.Log.Infof("%s of %s versions %v", operation, versionsRequest.Name, versionsRequest.Versions)
+
+	versions, err := context.Store.GetVersions(versionsRequest.Name)
+	if err != nil {
+		context.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem fetching versions by name: %s %s", versionsRequest.Name, err)))
+		return err
+	}
+
+	return context.JSON(http.StatusOK, struct {
+		Versions []secret.Version `json:"versions"`
+	}{
+		Versions: versions,
+	})
+}
+
+// Marks a version of a CA as transitional or unmarks it when no version is passed, the CA's certificates have to be
+// regenerated to pick up the change
+func transitionalVersionPostHandler(ctx echo.Context) error {
+	context := ctx.(*BvContext)
+	requestBody, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err))
+		return err
+	}
+
+	context.Log.Debugf("request: %s", requestBody)
+
+	var transitionalRequest types.TransitionalVersionRequest
+	err = json.Unmarshal(requestBody, &transitionalRequest)
+	if err != nil || transitionalRequest.Name == "" {
+		context.Log.Error("request error: ", err)
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, "transitional version requests must include a CA name"))
+		return errors.New("transitional version requests must include a CA name")
+	}
+
+	err = checkName(context, transitionalRequest.Name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationWrite, transitionalRequest.Name)
+	if err != nil {
+		return err
+	}
+
+	if !context.Store.Exists(transitionalRequest.Name) {
+		ctx.Error(echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no CA found by name: %s", transitionalRequest.Name)))
+		return errors.New(fmt.Sprintf("no CA found by name: %s", transitionalRequest.Name))
+	}
+
+	err = types.SetTransitionalVersion(context.Store, transitionalRequest.Name, transitionalRequest.Version)
+	if err != nil {
+		context.Log.Errorf("problem updating the transitional version of %s: %s", transitionalRequest.Name, err)
+		ctx.Error(echo.NewHTTPError(errorStatus(err, http.StatusBadRequest), fmt.Sprintf("problem updating the transitional version of %s: %s", transitionalRequest.Name, err)))
+		return err
+	}
+	context.Log.Infof("transitional version of %s set to %q", transitionalRequest.Name, transitionalRequest.Version)
+
+	return ctx.JSON(http.StatusOK, types.TransitionalVersionResponse{
+		Name:    transitionalRequest.Name,
+		Version: transitionalRequest.Version,
+	})
+}
+
+// Generates and stores a credential, then persists the request that generated it so it can be regenerated later
+func generateAndStore(context *BvContext, credentialRequest types.CredentialGenerationRequest) (types.CredentialResponse, error) {
+	credentialType := credentialRequest.CredentialType()
+
 	credential, err := credentialRequest.Generate(context.Store)
 	if err != nil {
 		context.Log.Error(err)
-		ctx.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem generating %s: %s", credentialType, err)))
+		context.Error(echo.NewHTTPError(errorStatus(err, http.StatusInternalServerError), fmt.Sprintf("problem generating %s: %s", credentialType, err)))
+		return nil, err
 	}
 
 	credentialResponse, err := credential.Store(context.Store, credentialRequest.CredentialName())
 	if err != nil {
 		context.Log.Error(err)
 		context.Error(echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("problem storing %s: %s", credentialType, credentialRequest.CredentialName())))
+		return nil, err
 	}
 
-	return ctx.JSON(http.StatusCreated, &credentialResponse)
+	// The credential itself was stored successfully so this isn't fatal, it just won't be possible to regenerate it
+	err = types.StoreGenerationRequest(context.Store, credentialRequest)
+	if err != nil {
+		context.Log.Errorf("problem storing generation parameters for %s: %s", credentialRequest.CredentialName(), err)
+	}
+
+	return credentialResponse, nil
 }
 
 func dataDeleteHandler(ctx echo.Context) error {
@@ -149,11 +572,33 @@ func dataDeleteHandler(ctx echo.Context) error {
 		return errors.New("name query param not passed to data?name handler")
 	}
 	context.Log.Debugf("request to DELETE %s?name=%s", dataUri, name)
-	err := context.Store.DeleteByName(name)
+	err := checkName(context, name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationDelete, name)
+	if err != nil {
+		return err
+	}
+
+	err = context.Store.DeleteByName(name)
 	if err != nil {
 		context.Log.Errorf("problem deleting secret by name: %s %s", name, err)
+		if !context.Store.Exists(name) {
+			ctx.Error(echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("problem deleting secret by name: %s %s", name, err)))
+		}
 		return err
 	}
+
+	// a deleted credential can't be regenerated, a new generation request starts over
+	err = types.DeleteGenerationRequest(context.Store, name)
+	if err != nil {
+		context.Log.Errorf("problem deleting generation parameters for %s: %s", name, err)
+	}
+	err = types.DeleteTransitionalVersion(context.Store, name)
+	if err != nil {
+		context.Log.Errorf("problem deleting the transitional version of %s: %s", name, err)
+	}
 	return ctx.NoContent(http.StatusNoContent)
 }
 
@@ -172,11 +617,69 @@ func dataPutHandler(ctx echo.Context) error {
 		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
 		return err
 	}
+	err = checkName(context, setRequest.Name)
+	if err != nil {
+		return err
+	}
+	err = authorize(context, config.PolicyOperationWrite, setRequest.Name)
+	if err != nil {
+		return err
+	}
+
 	response, err := setRequest.Record.Store(context.Store, setRequest.Name)
+	if types.IsInvalidRecord(err) {
+		context.Log.Error("request error: ", err)
+		ctx.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return err
+	}
 	if err != nil {
 		context.Log.Error("server error: ", err)
 		ctx.Error(echo.NewHTTPError(http.StatusInternalServerError, err.Error()))
 		return err
 	}
+
+	// a set value replaces the generated one, regenerating it would silently replace the value again
+	err = types.DeleteGenerationRequest(context.Store, setRequest.Name)
+	if err != nil {
+		context.Log.Errorf("problem deleting generation parameters for %s: %s", setRequest.Name, err)
+	}
 	return ctx.JSON(http.StatusOK, &response)
 }
+
+// Responds with a 400 for names the api can't be used with
+func checkName(context *BvContext, name string) error {
+	if types.IsReservedName(name) {
+		err := errors.New(fmt.Sprintf("%s is reserved for bosh-vault bookkeeping", name))
+		context.Log.Error(err)
+		context.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return err
+	}
+	if types.HasRelativeSegments(name) {
+		err := errors.New(fmt.Sprintf("%s must not contain . or .. segments", name))
+		context.Log.Error(err)
+		context.Error(echo.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Responds with a 403 when the client's policy doesn't allow the operation on the credential
+func authorize(context *BvContext, operation string, name string) error {
+	if context.Policy == nil {
+		return nil
+	}
+	err := context.Policy.Authorize(operation, name)
+	if err != nil {
+		context.Log.Error(err)
+		context.Error(echo.NewHTTPError(http.StatusForbidden, err.Error()))
+	}
+	return err
+}
+
+// Errors from the store are answered with the fallback status unless the client's policy denied the operation
+func errorStatus(err error, fallback int) int {
+	if policy.IsDenied(err) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
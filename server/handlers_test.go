@@ -0,0 +1,218 @@
+package server_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/server"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/labstack/echo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handlers", func() {
+	var secretStore *store.MemoryStore
+	var api *echo.Echo
+
+	request := func(method string, uri string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, uri, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	decode := func(recorder *httptest.ResponseRecorder) map[string]interface{} {
+		var response map[string]interface{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		return response
+	}
+
+	BeforeEach(func() {
+		var bvConfig config.Configuration
+		bvConfig.Debug.DisableAuth = true
+		secretStore = store.NewMemoryStore()
+		api = server.NewServer(bvConfig, secretStore)
+	})
+
+	Describe("health", func() {
+		It("reports a healthy store", func() {
+			response := request(http.MethodGet, "/v1/health", "")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)).To(HaveKey("store"))
+		})
+
+		It("reports an unhealthy store and refuses other requests", func() {
+			secretStore.SetHealthy(false)
+			Expect(request(http.MethodGet, "/v1/health", "").Code).To(Equal(http.StatusInternalServerError))
+			Expect(request(http.MethodGet, "/v1/data?name=/anything", "").Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Describe("data", func() {
+		It("generates a password and finds it by name and id", func() {
+			response := request(http.MethodPost, "/v1/data", `{"name": "/director/deployment/password", "type": "password"}`)
+			Expect(response.Code).To(Equal(http.StatusCreated))
+			generated := decode(response)
+			Expect(generated["value"]).ToNot(BeEmpty())
+
+			response = request(http.MethodGet, "/v1/data?name=/director/deployment/password", "")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			byName := decode(response)["data"].([]interface{})
+			Expect(byName).To(HaveLen(1))
+			Expect(byName[0].(map[string]interface{})["value"]).To(Equal(generated["value"]))
+
+			response = request(http.MethodGet, "/v1/data/"+generated["id"].(string), "")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)["value"]).To(Equal(generated["value"]))
+		})
+
+		It("doesn't overwrite existing credentials in no-overwrite mode", func() {
+			first := decode(request(http.MethodPost, "/v1/data", `{"name": "/kept", "type": "password"}`))
+			response := request(http.MethodPost, "/v1/data", `{"name": "/kept", "type": "password", "mode": "no-overwrite"}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)["id"]).To(Equal(first["id"]))
+		})
+
+		It("sets values and rejects malformed requests", func() {
+			response := request(http.MethodPut, "/v1/data", `{"name": "/set/value", "type": "value", "value": "hello"}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)["value"]).To(Equal("hello"))
+
+			Expect(request(http.MethodPut, "/v1/data", `not json`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/set/user", "type": "user", "value": {"username": "bosh", "password": "secret", "password_hash": "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"}}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPost, "/v1/data", `{"name": "/bad", "type": "not-a-type"}`).Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("returns not found for credentials that don't exist", func() {
+			Expect(request(http.MethodGet, "/v1/data?name=/missing", "").Code).To(Equal(http.StatusNotFound))
+			Expect(request(http.MethodGet, "/v1/data/not-an-id", "").Code).To(Equal(http.StatusNotFound))
+			Expect(request(http.MethodDelete, "/v1/data?name=/missing", "").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("finds credentials by path without generation parameters", func() {
+			request(http.MethodPost, "/v1/data", `{"name": "/path/deployment/a", "type": "password"}`)
+			request(http.MethodPost, "/v1/data", `{"name": "/path/deployment/b", "type": "password"}`)
+			request(http.MethodPost, "/v1/data", `{"name": "/path/deployment/c", "type": "password"}`)
+
+			response := request(http.MethodGet, "/v1/data?path=/&offset=1&limit=1", "")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			pathResponse := decode(response)
+			Expect(pathResponse["total"]).To(BeEquivalentTo(3))
+			Expect(pathResponse["credentials"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "/path/deployment/b"},
+			}))
+
+			Expect(request(http.MethodGet, "/v1/data?path=/&limit=-1", "").Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects names with relative segments", func() {
+			request(http.MethodPut, "/v1/data", `{"name": "/other/value", "type": "value", "value": "hello"}`)
+			Expect(request(http.MethodGet, "/v1/data?name=/director/../other/value", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodGet, "/v1/data?path=/director/..", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/director/./value", "type": "value", "value": "hello"}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodDelete, "/v1/data?name=/director/../other/value", "").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("regenerating", func() {
+		It("regenerates a credential with its stored parameters", func() {
+			first := decode(request(http.MethodPost, "/v1/data", `{"name": "/regenerated", "type": "password", "parameters": {"length": 12}}`))
+
+			response := request(http.MethodPost, "/v1/regenerate", `{"name": "/regenerated"}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			regenerated := decode(response)
+			Expect(regenerated["id"]).ToNot(Equal(first["id"]))
+			Expect(regenerated["value"]).To(HaveLen(12))
+		})
+
+		It("only regenerates generated credentials", func() {
+			request(http.MethodPut, "/v1/data", `{"name": "/set/only", "type": "value", "value": "hello"}`)
+			Expect(request(http.MethodPost, "/v1/regenerate", `{"name": "/set/only"}`).Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("doesn't regenerate a generated credential that was set since", func() {
+			request(http.MethodPost, "/v1/data", `{"name": "/set/later", "type": "password"}`)
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/set/later", "type": "password", "value": "chosen"}`).Code).To(Equal(http.StatusOK))
+			Expect(request(http.MethodPost, "/v1/regenerate", `{"name": "/set/later"}`).Code).To(Equal(http.StatusNotFound))
+			Expect(decode(request(http.MethodGet, "/v1/data?name=/set/later", ""))["data"].([]interface{})[0].(map[string]interface{})["value"]).To(Equal("chosen"))
+		})
+
+		It("keeps the stored parameters out of reach of the data api", func() {
+			request(http.MethodPost, "/v1/data", `{"name": "/forged", "type": "password"}`)
+			Expect(request(http.MethodGet, "/v1/data?name=/bosh-vault/generation-requests/forged", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/bosh-vault/generation-requests/forged", "type": "json", "value": {"request": "{}"}}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodDelete, "/v1/data?name=/bosh-vault/generation-requests/forged", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPost, "/v1/regenerate", `{"name": "/forged"}`).Code).To(Equal(http.StatusOK))
+		})
+
+		It("keeps the emulated KV1 history out of reach of the data api", func() {
+			request(http.MethodPut, "/v1/data", `{"name": "/kv1/value", "type": "value", "value": "hello"}`)
+			Expect(request(http.MethodGet, "/v1/data?name=/bosh-vault/kv1-history/metadata/kv1/value", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodGet, "/v1/data?name=/bosh-vault/kv1-history/versions/kv1/value/1", "").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/bosh-vault/kv1-history/metadata/kv1/value", "type": "json", "value": {"current_version": 7}}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPut, "/v1/data", `{"name": "/bosh-vault/kv1-history/versions/kv1/value/1", "type": "value", "value": "forged"}`).Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("rotating CAs", func() {
+		It("signs with the active version and trusts the transitional version whichever is newer", func() {
+			caOf := func() string {
+				leaf := decode(request(http.MethodPost, "/v1/data", `{"name": "/leaf", "type": "certificate", "parameters": {"ca": "/rotated/ca", "common_name": "leaf"}}`))
+				return leaf["value"].(map[string]interface{})["ca"].(string)
+			}
+			certificateOf := func(response map[string]interface{}) string {
+				return response["value"].(map[string]interface{})["certificate"].(string)
+			}
+
+			oldCa := decode(request(http.MethodPost, "/v1/data", `{"name": "/rotated/ca", "type": "certificate", "parameters": {"is_ca": true, "common_name": "old"}}`))
+			response := request(http.MethodPost, "/v1/regenerate", `{"name": "/rotated/ca", "set_as_transitional": true}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			newCa := decode(response)
+			Expect(caOf()).To(Equal(certificateOf(oldCa) + certificateOf(newCa)))
+
+			response = request(http.MethodPost, "/v1/update-transitional-version", `{"name": "/rotated/ca", "version": "`+oldCa["id"].(string)+`"}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)["version"]).To(Equal(oldCa["id"]))
+			Expect(caOf()).To(Equal(certificateOf(newCa) + certificateOf(oldCa)))
+
+			Expect(request(http.MethodPost, "/v1/update-transitional-version", `{"name": "/rotated/ca"}`).Code).To(Equal(http.StatusOK))
+			Expect(caOf()).To(Equal(certificateOf(newCa)))
+
+			Expect(request(http.MethodPost, "/v1/update-transitional-version", `{"name": "/rotated/ca", "version": "not-a-version"}`).Code).To(Equal(http.StatusBadRequest))
+			Expect(request(http.MethodPost, "/v1/update-transitional-version", `{"name": "/missing/ca"}`).Code).To(Equal(http.StatusNotFound))
+			Expect(request(http.MethodGet, "/v1/data?name=/bosh-vault/transitional-versions/rotated/ca", "").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("versions", func() {
+		It("deletes, undeletes, and destroys versions", func() {
+			request(http.MethodPut, "/v1/data", `{"name": "/versioned", "type": "value", "value": "first"}`)
+			request(http.MethodPut, "/v1/data", `{"name": "/versioned", "type": "value", "value": "second"}`)
+
+			Expect(request(http.MethodDelete, "/v1/data?name=/versioned", "").Code).To(Equal(http.StatusNoContent))
+			Expect(request(http.MethodGet, "/v1/data?name=/versioned", "").Code).To(Equal(http.StatusOK))
+
+			response := request(http.MethodGet, "/v1/versions?name=/versioned", "")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(decode(response)["versions"]).To(HaveLen(2))
+
+			response = request(http.MethodPost, "/v1/undelete", `{"name": "/versioned", "versions": [2]}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			latest := decode(request(http.MethodGet, "/v1/data?name=/versioned", ""))["data"].([]interface{})
+			Expect(latest).To(HaveLen(1))
+			Expect(latest[0].(map[string]interface{})["value"]).To(Equal("second"))
+
+			response = request(http.MethodPost, "/v1/destroy", `{"name": "/versioned", "versions": [1]}`)
+			Expect(response.Code).To(Equal(http.StatusOK))
+			versions := decode(response)["versions"].([]interface{})
+			Expect(versions[1].(map[string]interface{})["destroyed"]).To(BeTrue())
+
+			Expect(request(http.MethodPost, "/v1/undelete", `{"name": "/never-existed", "versions": [1]}`).Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -2,12 +2,15 @@ package server
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
 	"github.com/cloudfoundry-community/bosh-vault/config"
 	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/mtls"
+	"github.com/cloudfoundry-community/bosh-vault/policy"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
 	"github.com/cloudfoundry-community/bosh-vault/store"
-	"github.com/cloudfoundry-community/bosh-vault/uaa"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"github.com/sirupsen/logrus"
@@ -15,17 +18,27 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
 const healthUri = "/v1/health"
 const dataUri = "/v1/data"
+const regenerateUri = "/v1/regenerate"
+const bulkRegenerateUri = "/v1/bulk-regenerate"
+const transitionalVersionUri = "/v1/update-transitional-version"
+const certificatesUri = "/v1/certificates"
+const versionsUri = "/v1/versions"
+const undeleteUri = "/v1/undelete"
+const destroyUri = "/v1/destroy"
 
 type BvContext struct {
 	echo.Context
 	Config config.Configuration
 	Log    *logrus.Logger
 	Store  secret.Store
+	// nil when no policies are configured, the store already enforces it
+	Policy *policy.Policy
 }
 
 func ListenAndServe(bvConfig config.Configuration) {
@@ -34,53 +47,12 @@ func ListenAndServe(bvConfig config.Configuration) {
 	if (bvConfig.Tls.Cert == "" || bvConfig.Tls.Key == "") && !bvConfig.Debug.DisableTls {
 		logger.Log.Fatal("unable to start bosh-vault without tls_cert_path and tls_key_path being set")
 	}
-
-	e := echo.New()
-
-	e.HideBanner = true
-	e.HidePort = true
-
-	storeClient := store.GetStore(bvConfig)
-
-	// Support UAA Authorization if enabled (and broad authentication for now too)
-	// Will allow connections if JWT contains the expected audience claim
-	// Skip if UAA isn't enabled or the request is for the health endpoint
-	if !bvConfig.Debug.DisableAuth {
-		uaaClient := uaa.GetUaa(bvConfig)
-
-		e.Use(uaaClient.AuthMiddleware(uaa.MiddlewareConfig{
-			Skipper: func(c echo.Context) bool {
-				return c.Request().RequestURI == healthUri
-			},
-		}))
+	if bvConfig.Debug.DisableTls && !bvConfig.Debug.DisableAuth && authProviderConfigured(bvConfig, config.AuthProviderMtls) {
+		logger.Log.Fatal("the mtls auth provider can't authenticate anything with tls disabled")
 	}
 
-	// middleware function that sets a custom context exposing our configuration and logger to handler functions
-	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// return 500 if the store isn't healthy
-			if !storeClient.Healthy() {
-				return echo.NewHTTPError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
-			}
-			configContext := &BvContext{
-				Context: c,
-				Config:  bvConfig,
-				Log:     logger.Log,
-				Store:   storeClient,
-			}
-			return next(configContext)
-		}
-	})
-
-	e.Use(middleware.Secure())
-
-	e.GET(healthUri, healthCheckHandler)
-
-	e.POST(dataUri, dataPostHandler)
-	e.PUT(dataUri, dataPutHandler)
-	e.GET(fmt.Sprintf("%s/:id", dataUri), dataGetByIdHandler)
-	e.GET(dataUri, dataGetByNameHandler)
-	e.DELETE(dataUri, dataDeleteHandler)
+	storeClient := store.GetStore(bvConfig)
+	e := NewServer(bvConfig, storeClient)
 
 	// Start server
 	go func() {
@@ -91,23 +63,9 @@ func ListenAndServe(bvConfig config.Configuration) {
 				logger.Log.Info("shutting down the bosh-vault api server")
 			}
 		} else {
-			// setup custom TLS config and HTTP server to ensure TLS1.2
-			cert, err := tls.LoadX509KeyPair(bvConfig.Tls.Cert, bvConfig.Tls.Key)
+			tlsConfig, err := TlsConfig(bvConfig)
 			if err != nil {
-				logger.Log.Fatal("Can't load certificates for TLS")
-			}
-
-			tlsConfig := &tls.Config{
-				MinVersion:               tls.VersionTLS12,
-				MaxVersion:               tls.VersionTLS12,
-				PreferServerCipherSuites: true,
-				Certificates:             []tls.Certificate{cert},
-				CipherSuites: []uint16{
-					tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-					tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_RSA_WITH_AES_256_CBC_SHA,
-				},
+				logger.Log.Fatal(err)
 			}
 
 			server := &http.Server{
@@ -122,8 +80,8 @@ func ListenAndServe(bvConfig config.Configuration) {
 	}()
 
 	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	// Gracefully shutdown the server if it has not shutdown within 10 seconds then force it to shutdown
 	logger.Log.Info("received shutdown signal, shutting down the bosh-vault api server")
@@ -132,4 +90,127 @@ func ListenAndServe(bvConfig config.Configuration) {
 	if err := e.Shutdown(ctx); err != nil {
 		logger.Log.Error(err)
 	}
+	storeClient.Close()
+}
+
+// Setup custom TLS config to ensure TLS1.2. With the mtls auth provider client certificates are verified with the client
+// CA, they're required when it's the only provider and optional when tokens can be used as well.
+func TlsConfig(bvConfig config.Configuration) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(bvConfig.Tls.Cert, bvConfig.Tls.Key)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("can't load certificates for TLS: %s", err))
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:               tls.VersionTLS12,
+		MaxVersion:               tls.VersionTLS12,
+		PreferServerCipherSuites: true,
+		Certificates:             []tls.Certificate{cert},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+	}
+
+	if !bvConfig.Debug.DisableAuth && authProviderConfigured(bvConfig, config.AuthProviderMtls) {
+		tlsConfig.ClientCAs, err = mtls.ClientCaPool(bvConfig.Tls.ClientCa)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if len(bvConfig.Auth.Providers) == 1 {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
+
+// Sets up the api's middleware and routes on top of a store, ListenAndServe serves it with the store from the config
+func NewServer(bvConfig config.Configuration, storeClient secret.Store) *echo.Echo {
+	e := echo.New()
+
+	e.HideBanner = true
+	e.HidePort = true
+
+	// Authenticate requests with the configured providers unless auth is disabled, skipping the health endpoint
+	// Routes then check the scopes the identity's provider requires for reading, writing, or deleting
+	readScopes := map[string][]string{}
+	writeScopes := map[string][]string{}
+	deleteScopes := map[string][]string{}
+	if !bvConfig.Debug.DisableAuth {
+		providers := authProviders(bvConfig)
+		e.Use(auth.Middleware(func(c echo.Context) bool {
+			return c.Request().RequestURI == healthUri
+		}, providers...))
+
+		for _, provider := range providers {
+			scopes := providerScopes(bvConfig, provider.Name())
+			readScopes[provider.Name()] = scopes.Read
+			writeScopes[provider.Name()] = scopes.Write
+			deleteScopes[provider.Name()] = scopes.Delete
+		}
+	}
+	read := auth.RequireScopes(readScopes)
+	write := auth.RequireScopes(writeScopes)
+	remove := auth.RequireScopes(deleteScopes)
+
+	var policies *policy.Policies
+	if len(bvConfig.Policies) > 0 {
+		if bvConfig.Debug.DisableAuth {
+			logger.Log.Error("policies can't be enforced without auth, every client may use every credential")
+		} else {
+			var err error
+			policies, err = policy.NewPolicies(bvConfig.Policies)
+			if err != nil {
+				logger.Log.Fatal(err)
+			}
+		}
+	}
+
+	// middleware function that sets a custom context exposing our configuration and logger to handler functions
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// return 500 if the store isn't healthy, the health endpoint reports why itself
+			if c.Request().RequestURI != healthUri && !storeClient.Healthy() {
+				return echo.NewHTTPError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			}
+			configContext := &BvContext{
+				Context: c,
+				Config:  bvConfig,
+				Log:     logger.Log,
+				Store:   storeClient,
+			}
+			if policies != nil && c.Request().RequestURI != healthUri {
+				identity, _ := auth.GetIdentity(c)
+				clientPolicy := policies.For(identity.Provider, identity.Id)
+				configContext.Policy = &clientPolicy
+				configContext.Store = policy.NewStore(storeClient, clientPolicy)
+			}
+			return next(configContext)
+		}
+	})
+
+	e.Use(middleware.Secure())
+
+	e.GET(healthUri, healthCheckHandler)
+
+	e.POST(dataUri, dataPostHandler, write)
+	e.PUT(dataUri, dataPutHandler, write)
+	e.GET(fmt.Sprintf("%s/:id", dataUri), dataGetByIdHandler, read)
+	e.GET(dataUri, dataGetHandler, read)
+	e.DELETE(dataUri, dataDeleteHandler, remove)
+
+	e.POST(regenerateUri, regeneratePostHandler, write)
+	e.POST(bulkRegenerateUri, bulkRegeneratePostHandler, write)
+	e.POST(transitionalVersionUri, transitionalVersionPostHandler, write)
+
+	e.GET(certificatesUri, certificatesGetHandler, read)
+
+	e.GET(versionsUri, versionsGetHandler, read)
+	e.POST(undeleteUri, undeletePostHandler, write)
+	e.POST(destroyUri, destroyPostHandler, remove)
+
+	return e
 }
@@ -0,0 +1,19 @@
+package server_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	// Make sure logger singleton is available
+	logger.Log = logrus.New()
+	logger.Log.Out = ioutil.Discard
+
+	RunSpecs(t, "Server Suite")
+}
@@ -0,0 +1,183 @@
+package server_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/server"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mutual TLS", func() {
+	var dir string
+	var caCert *x509.Certificate
+	var caKey *rsa.PrivateKey
+	var caPool *x509.CertPool
+	var clientCert tls.Certificate
+	var bvConfig config.Configuration
+	var api *httptest.Server
+
+	issue := func(template *x509.Certificate, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		template.SerialNumber = big.NewInt(time.Now().UnixNano())
+		template.NotBefore = time.Now().Add(-time.Minute)
+		template.NotAfter = time.Now().Add(time.Hour)
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		Expect(err).ToNot(HaveOccurred())
+		certificate, err := x509.ParseCertificate(der)
+		Expect(err).ToNot(HaveOccurred())
+		return certificate, key
+	}
+
+	write := func(name string, certificate *x509.Certificate, key *rsa.PrivateKey) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}), 0600)).To(Succeed())
+		if key != nil {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)).To(Succeed())
+		}
+	}
+
+	request := func(method string, uri string, body string, certificates ...tls.Certificate) (int, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      caPool,
+			Certificates: certificates,
+		}}}
+		req, err := http.NewRequest(method, api.URL+uri, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "bosh-vault-mtls")
+		Expect(err).ToNot(HaveOccurred())
+
+		caCert, caKey = issue(&x509.Certificate{
+			Subject:               pkix.Name{CommonName: "bosh-vault-ca"},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil, nil)
+		caPool = x509.NewCertPool()
+		caPool.AddCert(caCert)
+		write("ca", caCert, nil)
+
+		serverCert, serverKey := issue(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: "bosh-vault"},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}, caCert, caKey)
+		write("server", serverCert, serverKey)
+
+		directorCert, directorKey := issue(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: "director-a"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, caCert, caKey)
+		clientCert = tls.Certificate{Certificate: [][]byte{directorCert.Raw}, PrivateKey: directorKey}
+
+		bvConfig = config.ParseConfig(nil)
+		bvConfig.Tls.Cert = filepath.Join(dir, "server.crt")
+		bvConfig.Tls.Key = filepath.Join(dir, "server.key")
+		bvConfig.Tls.ClientCa = filepath.Join(dir, "ca.crt")
+		bvConfig.Auth.Providers = []string{config.AuthProviderMtls}
+		bvConfig.Policies = []config.PolicyConfiguration{
+			{
+				Provider: config.AuthProviderMtls,
+				Clients:  []string{"director-a"},
+				Paths:    []config.PolicyPath{{Prefix: "/director-a", Operations: []string{"read", "write"}}},
+			},
+			// a UAA client with the same name as the certificate
+			{
+				Clients: []string{"director-a"},
+				Paths:   []config.PolicyPath{{Prefix: "/director-b", Operations: []string{"read", "write"}}},
+			},
+		}
+	})
+
+	start := func() {
+		tlsConfig, err := server.TlsConfig(bvConfig)
+		Expect(err).ToNot(HaveOccurred())
+		api = httptest.NewUnstartedServer(server.NewServer(bvConfig, store.NewMemoryStore()))
+		api.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		api.TLS = tlsConfig
+		api.StartTLS()
+	}
+
+	AfterEach(func() {
+		if api != nil {
+			api.Close()
+			api = nil
+		}
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("identifies clients by their certificate", func() {
+		start()
+		code, err := request(http.MethodPost, "/v1/data", `{"name": "/director-a/password", "type": "password"}`, clientCert)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(code).To(Equal(http.StatusCreated))
+
+		code, err = request(http.MethodPost, "/v1/data", `{"name": "/director-b/password", "type": "password"}`, clientCert)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(code).To(Equal(http.StatusForbidden))
+	})
+
+	It("requires a certificate signed by the client CA", func() {
+		start()
+		_, err := request(http.MethodGet, "/v1/data?name=/director-a/password", "")
+		Expect(err).To(HaveOccurred())
+
+		otherCa, otherCaKey := issue(&x509.Certificate{
+			Subject:               pkix.Name{CommonName: "other-ca"},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil, nil)
+		otherCert, otherKey := issue(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: "director-a"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, otherCa, otherCaKey)
+		_, err = request(http.MethodGet, "/v1/data?name=/director-a/password", "", tls.Certificate{Certificate: [][]byte{otherCert.Raw}, PrivateKey: otherKey})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("lets clients connect without a certificate when tokens can be used as well", func() {
+		bvConfig.Auth.Providers = []string{config.AuthProviderUaa, config.AuthProviderMtls}
+		tlsConfig, err := server.TlsConfig(bvConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tlsConfig.ClientAuth).To(Equal(tls.VerifyClientCertIfGiven))
+		Expect(tlsConfig.ClientCAs).ToNot(BeNil())
+	})
+
+	It("needs a client CA", func() {
+		bvConfig.Tls.ClientCa = ""
+		_, err := server.TlsConfig(bvConfig)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"sort"
+	"strings"
+)
+
+// Builds the secret.Store for a backend from the configuration
+type Backend func(bvConfig config.Configuration) (secret.Store, error)
+
+var backends = map[string]Backend{}
+
+func init() {
+	RegisterBackend(config.StoreBackendVault, getVaultStore)
+	RegisterBackend(config.StoreBackendFile, func(bvConfig config.Configuration) (secret.Store, error) {
+		return NewFileStore(bvConfig.Store.File)
+	})
+}
+
+// Makes a backend selectable with store.backend in the configuration, registering a name again replaces its backend
+func RegisterBackend(name string, backend Backend) {
+	backends[name] = backend
+}
+
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewStore(bvConfig config.Configuration) (secret.Store, error) {
+	backendName := bvConfig.Store.Backend
+	if backendName == "" {
+		backendName = config.DefaultStoreBackend
+	}
+
+	backend, ok := backends[backendName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown store backend %s, must be one of: %s", backendName, strings.Join(Backends(), ", ")))
+	}
+	if bvConfig.Store.Transit.Key != "" && backendName != config.StoreBackendVault {
+		return nil, errors.New(fmt.Sprintf("transit encryption needs the %s store backend", config.StoreBackendVault))
+	}
+	return backend(bvConfig)
+}
+
+// Like NewStore but a store that can't be built is fatal
+func GetStore(bvConfig config.Configuration) secret.Store {
+	secretStore, err := NewStore(bvConfig)
+	if err != nil {
+		logger.Log.Fatal(err)
+	}
+	return secretStore
+}
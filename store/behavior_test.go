@@ -0,0 +1,72 @@
+package store_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The memory store stands in for Vault in other tests so both have to answer the same way
+var _ = Describe("Store behavior", func() {
+	backends := map[string]func(deletePolicy string) secret.Store{
+		"vault kv2": func(deletePolicy string) secret.Store {
+			policyStore := healthySimpleStore
+			policyStore.Vault.Config.DeletePolicy = deletePolicy
+			return &policyStore
+		},
+		"memory": func(deletePolicy string) secret.Store {
+			memoryStore := store.NewMemoryStore()
+			memoryStore.SetDeletePolicy(deletePolicy)
+			return memoryStore
+		},
+	}
+
+	for backendName, newStore := range backends {
+		backendName, newStore := backendName, newStore
+
+		Context("with the "+backendName+" store", func() {
+			It("only returns live versions by name", func() {
+				secretStore := newStore(config.DeletePolicyLatestSoft)
+				name := "/behavior/latest-soft"
+				firstId, err := secretStore.Set(name, map[string]interface{}{"value": "first"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = secretStore.Set(name, map[string]interface{}{"value": "second"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(secretStore.DeleteByName(name)).To(Succeed())
+
+				versions, err := secretStore.GetByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(versions).To(HaveLen(1))
+				Expect(versions[0].Id).To(Equal(firstId))
+				Expect(versions[0].Value).To(Equal(map[string]interface{}{"value": "first"}))
+
+				latest, err := secretStore.GetLatestByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(latest.Id).To(Equal(firstId))
+				Expect(secretStore.Exists(name)).To(BeFalse())
+			})
+
+			It("has no latest version once every version is deleted", func() {
+				secretStore := newStore(config.DeletePolicyAllSoft)
+				name := "/behavior/all-soft"
+				_, err := secretStore.Set(name, map[string]interface{}{"value": "first"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(secretStore.DeleteByName(name)).To(Succeed())
+
+				versions, err := secretStore.GetByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(versions).To(BeEmpty())
+				_, err = secretStore.GetLatestByName(name)
+				Expect(err).To(HaveOccurred())
+
+				Expect(secretStore.Undelete(name, []int{1})).To(Succeed())
+				latest, err := secretStore.GetLatestByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(latest.Value).To(Equal(map[string]interface{}{"value": "first"}))
+			})
+		})
+	}
+})
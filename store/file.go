@@ -0,0 +1,278 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const fileStoreFormatVersion = 1
+
+// scrypt parameters recommended for interactive logins in 2017, the key is only derived once on startup
+const fileStoreScryptN = 32768
+const fileStoreScryptR = 8
+const fileStoreScryptP = 1
+
+// Keeps every secret in a single file encrypted with AES-256-GCM, for running bosh-vault without a Vault (e.g. with
+// bosh create-env or in CI). Every change rewrites the whole file so it's meant for the number of credentials a single
+// director has, and only one bosh-vault process may use the file at a time.
+type FileStore struct {
+	Path string
+
+	secrets *versionedSecrets
+	key     []byte
+	salt    []byte
+
+	mutex     sync.RWMutex
+	lastError error
+}
+
+// The file is stored as JSON with the encrypted secrets and what is needed to decrypt them with the passphrase
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Opens the file at the configured path, creating it if it doesn't exist yet
+func NewFileStore(fileConfig config.FileStoreConfiguration) (*FileStore, error) {
+	if fileConfig.Path == "" || fileConfig.KeyFile == "" {
+		return nil, errors.New("the file store requires a path and a keyfile")
+	}
+
+	err := config.ValidateDeletePolicy(fileConfig.DeletePolicy)
+	if err != nil {
+		return nil, err
+	}
+	if fileConfig.DeletePolicy == "" {
+		fileConfig.DeletePolicy = config.DefaultVaultDeletePolicy
+	}
+
+	passphrase, err := ioutil.ReadFile(fileConfig.KeyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("problem reading file store key from %s: %s", fileConfig.KeyFile, err))
+	}
+	if strings.TrimSpace(string(passphrase)) == "" {
+		return nil, errors.New(fmt.Sprintf("file store key %s is empty", fileConfig.KeyFile))
+	}
+
+	fs := &FileStore{
+		Path:    fileConfig.Path,
+		secrets: newVersionedSecrets(fileConfig.DeletePolicy),
+	}
+
+	fileContents, err := ioutil.ReadFile(fileConfig.Path)
+	switch {
+	case os.IsNotExist(err):
+		fs.salt = make([]byte, 32)
+		_, err = rand.Read(fs.salt)
+		if err != nil {
+			return nil, err
+		}
+		fs.key, err = deriveFileStoreKey(passphrase, fs.salt)
+		if err != nil {
+			return nil, err
+		}
+		logger.Log.Infof("creating file store %s", fileConfig.Path)
+		// writing the empty store right away makes a path that isn't writable fail on startup
+		err = fs.save([]byte("{}"))
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, errors.New(fmt.Sprintf("problem reading file store %s: %s", fileConfig.Path, err))
+	default:
+		err = fs.open(fileContents, passphrase)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("problem opening file store %s: %s", fileConfig.Path, err))
+		}
+	}
+
+	fs.secrets.save = fs.save
+	return fs, nil
+}
+
+func (fs *FileStore) open(fileContents []byte, passphrase []byte) error {
+	var file encryptedFile
+	err := json.Unmarshal(fileContents, &file)
+	if err != nil {
+		return err
+	}
+	if file.Version != fileStoreFormatVersion {
+		return errors.New(fmt.Sprintf("unknown file store format version %d", file.Version))
+	}
+
+	fs.salt = file.Salt
+	fs.key, err = deriveFileStoreKey(passphrase, fs.salt)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := fs.cipher()
+	if err != nil {
+		return err
+	}
+	secretsJson, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return errors.New("could not decrypt, is the keyfile the one the store was created with?")
+	}
+	return fs.secrets.load(secretsJson)
+}
+
+// Encrypts with a new nonce and replaces the file by renaming so a failed write never leaves a partial file behind
+func (fs *FileStore) save(secretsJson []byte) error {
+	err := fs.write(secretsJson)
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.lastError = err
+	if err != nil {
+		logger.Log.Errorf("problem writing file store %s: %s", fs.Path, err)
+	}
+	return err
+}
+
+func (fs *FileStore) write(secretsJson []byte) error {
+	gcm, err := fs.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	fileContents, err := json.Marshal(encryptedFile{
+		Version:    fileStoreFormatVersion,
+		Salt:       fs.salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, secretsJson, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(fileContents)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	closeErr := tempFile.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(tempFile.Name(), fs.Path)
+}
+
+func (fs *FileStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(fs.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveFileStoreKey(passphrase []byte, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(strings.TrimSpace(string(passphrase))), salt, fileStoreScryptN, fileStoreScryptR, fileStoreScryptP, 32)
+}
+
+// Unhealthy while the file can't be written, after a failed write the file is written again to check
+func (fs *FileStore) Healthy() bool {
+	fs.mutex.RLock()
+	lastError := fs.lastError
+	fs.mutex.RUnlock()
+
+	if lastError == nil {
+		return true
+	}
+	return fs.secrets.resave() == nil
+}
+
+func (fs *FileStore) Status() map[string]interface{} {
+	// counted before locking, saving locks the store while the secrets are locked
+	fileStatus := map[string]interface{}{
+		"path":    fs.Path,
+		"secrets": fs.secrets.count(),
+	}
+
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	if fs.lastError != nil {
+		fileStatus["last_error"] = fs.lastError.Error()
+	}
+	return map[string]interface{}{
+		"file": fileStatus,
+	}
+}
+
+// Every change is written when it's made so there is nothing left to do
+func (fs *FileStore) Close() {}
+
+func (fs *FileStore) Exists(name string) bool {
+	return fs.secrets.exists(name)
+}
+
+func (fs *FileStore) GetLatestByName(name string) (secret.Secret, error) {
+	secrets, err := fs.GetByName(name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	if len(secrets) == 0 {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+	return secrets[0], nil
+}
+
+func (fs *FileStore) GetByName(name string) ([]secret.Secret, error) {
+	return fs.secrets.getByName(name)
+}
+
+func (fs *FileStore) GetById(id string) (secret.Secret, error) {
+	return fs.secrets.getById(id)
+}
+
+func (fs *FileStore) Set(name string, value interface{}) (string, error) {
+	return fs.secrets.set(name, value)
+}
+
+func (fs *FileStore) DeleteByName(name string) error {
+	return fs.secrets.deleteByName(name)
+}
+
+func (fs *FileStore) List(prefix string) ([]string, error) {
+	return fs.secrets.list(prefix), nil
+}
+
+func (fs *FileStore) GetVersions(name string) ([]secret.Version, error) {
+	return fs.secrets.getVersions(name)
+}
+
+func (fs *FileStore) Undelete(name string, versions []int) error {
+	return fs.secrets.undelete(name, versions)
+}
+
+func (fs *FileStore) Destroy(name string, versions []int) error {
+	return fs.secrets.destroy(name, versions)
+}
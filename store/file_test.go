@@ -0,0 +1,147 @@
+package store_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Store", func() {
+	var storeDirectory string
+	var fileConfig config.FileStoreConfiguration
+	var fileStore *store.FileStore
+
+	BeforeEach(func() {
+		var err error
+		storeDirectory, err = ioutil.TempDir("", "bosh-vault-file-store")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileConfig = config.FileStoreConfiguration{
+			Path:    filepath.Join(storeDirectory, "credentials.json"),
+			KeyFile: filepath.Join(storeDirectory, "key"),
+		}
+		Expect(ioutil.WriteFile(fileConfig.KeyFile, []byte("a very secret passphrase\n"), 0600)).To(Succeed())
+
+		fileStore, err = store.NewFileStore(fileConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(storeDirectory)
+	})
+
+	It("is healthy and creates the file on startup", func() {
+		Expect(fileStore.Healthy()).To(BeTrue())
+		Expect(fileConfig.Path).To(BeAnExistingFile())
+	})
+
+	It("versions secrets with ids compatible with the vault backend", func() {
+		firstId, err := fileStore.Set("/deployment/password", map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(firstId).To(Equal(fileStoreId("/deployment/password", "1")))
+		_, err = fileStore.Set("/deployment/password", map[string]interface{}{"value": "second"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fileStore.Exists("/deployment/password")).To(BeTrue())
+		Expect(fileStore.Exists("/deployment/other")).To(BeFalse())
+
+		secrets, err := fileStore.GetByName("/deployment/password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secrets).To(HaveLen(2))
+		Expect(secrets[0].Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+		first, err := fileStore.GetById(firstId)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Value).To(Equal(map[string]interface{}{"value": "first"}))
+
+		latest, err := fileStore.GetById(fileStoreId("/deployment/password", "0"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+	})
+
+	It("keeps secrets encrypted across restarts", func() {
+		_, err := fileStore.Set("/deployment/password", map[string]interface{}{"value": "persisted", "length": 9})
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(fileConfig.Path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).ToNot(ContainSubstring("persisted"))
+
+		reopenedStore, err := store.NewFileStore(fileConfig)
+		Expect(err).ToNot(HaveOccurred())
+		latest, err := reopenedStore.GetLatestByName("/deployment/password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "persisted", "length": json.Number("9")}))
+
+		Expect(ioutil.WriteFile(fileConfig.KeyFile, []byte("the wrong passphrase"), 0600)).To(Succeed())
+		_, err = store.NewFileStore(fileConfig)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("deletes, undeletes, and destroys versions", func() {
+		name := "/deployment/versions"
+		_, err := fileStore.Set(name, map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = fileStore.Set(name, map[string]interface{}{"value": "second"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fileStore.DeleteByName(name)).To(Succeed())
+		Expect(fileStore.Exists(name)).To(BeFalse())
+		Expect(fileStore.DeleteByName(name)).ToNot(Succeed())
+
+		versions, err := fileStore.GetVersions(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[0].Version).To(Equal(2))
+		Expect(versions[0].DeletionTime).ToNot(BeEmpty())
+		Expect(versions[1].DeletionTime).ToNot(BeEmpty())
+
+		Expect(fileStore.Undelete(name, []int{2})).To(Succeed())
+		latest, err := fileStore.GetLatestByName(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+		Expect(fileStore.Destroy(name, []int{1})).To(Succeed())
+		versions, err = fileStore.GetVersions(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions[1].Destroyed).To(BeTrue())
+		_, err = fileStore.GetById(versions[1].Id)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("lists secrets under a path prefix", func() {
+		for _, name := range []string{"/listing/deployment/password", "/listing/deployment/nested/certificate", "/listing_sibling/password"} {
+			_, err := fileStore.Set(name, map[string]interface{}{"value": "listed"})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		names, err := fileStore.List("/listing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(ConsistOf("/listing/deployment/password", "/listing/deployment/nested/certificate"))
+	})
+
+	It("is selected by the store backend configuration", func() {
+		var bvConfig config.Configuration
+		bvConfig.Store.Backend = config.StoreBackendFile
+		bvConfig.Store.File = fileConfig
+		secretStore, err := store.NewStore(bvConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secretStore).To(BeAssignableToTypeOf(&store.FileStore{}))
+
+		bvConfig.Store.Backend = "floppy-disk"
+		_, err = store.NewStore(bvConfig)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func fileStoreId(name string, version string) string {
+	id, err := store.EncodeId(store.VersionedSecretMetaData{Name: name, Version: json.Number(version)})
+	Expect(err).ToNot(HaveOccurred())
+	return id
+}
@@ -0,0 +1,488 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vault's KV1 secret engine only keeps a single value per path. The KV1 store keeps the latest version of a secret at its
+// own path, so anything else reading the mount still sees the current value, and emulates KV2 versioning by copying
+// every version along with KV2 style metadata to a history path under KV1HistoryPrefix. Secrets written to the mount
+// before bosh-vault managed it have no history and are treated as version 1 until they are written again.
+//
+// KV1 has no check-and-set so concurrent writes of the same secret can race, run a single bosh-vault per KV1 mount.
+const KV1HistoryPrefix = "/bosh-vault/kv1-history"
+
+type KV1Store struct {
+	Vault vault.Vault
+}
+
+type kv1History struct {
+	CurrentVersion int                           `json:"current_version"`
+	Versions       map[string]kv1VersionMetadata `json:"versions"`
+	// the secret exists at its path but has no history yet
+	legacy bool
+}
+
+type kv1VersionMetadata struct {
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time"`
+	Destroyed    bool   `json:"destroyed"`
+}
+
+func (h *kv1History) live(version int) bool {
+	metadata, ok := h.Versions[strconv.Itoa(version)]
+	return ok && metadata.DeletionTime == "" && !metadata.Destroyed
+}
+
+func (ks *KV1Store) Healthy() bool {
+	return ks.Vault.Healthy()
+}
+
+func (ks *KV1Store) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"vault": ks.Vault.Status(),
+	}
+}
+
+func (ks *KV1Store) Close() {
+	ks.Vault.Close()
+}
+
+func (ks *KV1Store) Exists(name string) bool {
+	return ks.Vault.ExistsV1(name)
+}
+
+func (ks *KV1Store) GetLatestByName(name string) (secret.Secret, error) {
+	secrets, err := ks.GetByName(name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	if len(secrets) == 0 {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+	return secrets[0], nil
+}
+
+// Every live version of the secret newest first, deleted and destroyed versions are skipped
+func (ks *KV1Store) GetByName(name string) ([]secret.Secret, error) {
+	secrets := make([]secret.Secret, 0)
+
+	history, err := ks.history(name)
+	if err != nil {
+		return secrets, err
+	}
+	if history.CurrentVersion == 0 {
+		return secrets, errors.New("secret not found")
+	}
+
+	for version := history.CurrentVersion; version > 0; version-- {
+		if !history.live(version) {
+			continue
+		}
+		s, err := ks.getVersion(name, version, history)
+		if err != nil {
+			logger.Log.Errorf("Problem fetching secret: %s version %d", name, version)
+			continue
+		}
+		secrets = append(secrets, s)
+	}
+
+	return secrets, nil
+}
+
+func (ks *KV1Store) GetById(id string) (secret.Secret, error) {
+	decodedId, err := DecodeId(id)
+	if err != nil {
+		return secret.Secret{}, errors.New("malformed or invalid id")
+	}
+	version, err := decodedId.Version.Int64()
+	if err != nil {
+		return secret.Secret{}, errors.New("malformed or invalid id")
+	}
+
+	history, err := ks.history(decodedId.Name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	// like KV2, version 0 is the latest version
+	if version == 0 {
+		version = int64(history.CurrentVersion)
+	}
+	if !history.live(int(version)) {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+
+	s, err := ks.getVersion(decodedId.Name, int(version), history)
+	if err != nil {
+		return s, err
+	}
+	s.Id = id
+	return s, nil
+}
+
+func (ks *KV1Store) Set(name string, value interface{}) (string, error) {
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return "", errors.New(fmt.Sprintf("values stored in a kv1 mount must be objects, got %T", value))
+	}
+
+	history, err := ks.history(name)
+	if err != nil {
+		return "", err
+	}
+	err = ks.adopt(name, &history)
+	if err != nil {
+		return "", err
+	}
+
+	version := history.CurrentVersion + 1
+	err = ks.Vault.SetV1(kv1VersionPath(name, version), data)
+	if err != nil {
+		logger.Log.Error(err)
+		return "", err
+	}
+	history.CurrentVersion = version
+	history.Versions[strconv.Itoa(version)] = kv1VersionMetadata{
+		CreatedTime: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	err = ks.saveHistory(name, history)
+	if err != nil {
+		logger.Log.Error(err)
+		return "", err
+	}
+
+	err = ks.Vault.SetV1(name, data)
+	if err != nil {
+		logger.Log.Error(err)
+		return "", err
+	}
+
+	return EncodeId(VersionedSecretMetaData{
+		Name:    name,
+		Version: json.Number(strconv.Itoa(version)),
+	})
+}
+
+// Deletes according to the Vault's delete policy, it's an error to delete a secret without any live versions
+func (ks *KV1Store) DeleteByName(name string) error {
+	history, err := ks.history(name)
+	if err != nil {
+		return err
+	}
+
+	liveVersions := make([]int, 0)
+	for version := history.CurrentVersion; version > 0; version-- {
+		if history.live(version) {
+			liveVersions = append(liveVersions, version)
+		}
+	}
+	if len(liveVersions) == 0 {
+		return errors.New("secret not found")
+	}
+
+	err = ks.adopt(name, &history)
+	if err != nil {
+		return err
+	}
+
+	switch ks.Vault.Config.DeletePolicy {
+	case config.DeletePolicyLatestSoft:
+		if liveVersions[0] != history.CurrentVersion {
+			return errors.New("secret not found")
+		}
+		liveVersions = liveVersions[:1]
+	case config.DeletePolicyMetadataDestroy:
+		for versionKey := range history.Versions {
+			version, _ := strconv.Atoi(versionKey)
+			err = ks.Vault.DeleteV1(kv1VersionPath(name, version))
+			if err != nil {
+				return err
+			}
+		}
+		err = ks.Vault.DeleteV1(kv1MetadataPath(name))
+		if err != nil {
+			return err
+		}
+		return ks.Vault.DeleteV1(name)
+	}
+
+	deletionTime := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, version := range liveVersions {
+		metadata := history.Versions[strconv.Itoa(version)]
+		metadata.DeletionTime = deletionTime
+		history.Versions[strconv.Itoa(version)] = metadata
+	}
+	return ks.saveHistoryAndLatest(name, history)
+}
+
+// Names of every secret under the prefix, the version history is internal to the store and never listed
+func (ks *KV1Store) List(prefix string) ([]string, error) {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	names := make([]string, 0)
+	keys, err := ks.Vault.ListV1(prefix)
+	if err != nil {
+		return names, err
+	}
+
+	for _, key := range keys {
+		if prefix+key == KV1HistoryPrefix+"/" {
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			nestedNames, err := ks.List(prefix + key)
+			if err != nil {
+				return names, err
+			}
+			names = append(names, nestedNames...)
+		} else {
+			names = append(names, prefix+key)
+		}
+	}
+
+	return names, nil
+}
+
+// Every version of the secret newest first, including soft deleted and destroyed versions
+func (ks *KV1Store) GetVersions(name string) ([]secret.Version, error) {
+	versions := make([]secret.Version, 0)
+
+	history, err := ks.history(name)
+	if err != nil {
+		return versions, err
+	}
+	if history.CurrentVersion == 0 {
+		return versions, errors.New("secret not found")
+	}
+
+	for versionKey, metadata := range history.Versions {
+		versionNumber, err := strconv.Atoi(versionKey)
+		if err != nil {
+			continue
+		}
+		id, err := EncodeId(VersionedSecretMetaData{
+			Name:    name,
+			Version: json.Number(versionKey),
+		})
+		if err != nil {
+			return versions, err
+		}
+		versions = append(versions, secret.Version{
+			Id:           id,
+			Version:      versionNumber,
+			CreatedTime:  metadata.CreatedTime,
+			DeletionTime: metadata.DeletionTime,
+			Destroyed:    metadata.Destroyed,
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// Restores soft deleted versions, destroyed versions can't be recovered
+func (ks *KV1Store) Undelete(name string, versions []int) error {
+	history, err := ks.history(name)
+	if err != nil {
+		return err
+	}
+	if history.CurrentVersion == 0 {
+		return errors.New("secret not found")
+	}
+	err = ks.adopt(name, &history)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		metadata, ok := history.Versions[strconv.Itoa(version)]
+		if !ok || metadata.Destroyed {
+			continue
+		}
+		metadata.DeletionTime = ""
+		history.Versions[strconv.Itoa(version)] = metadata
+	}
+	return ks.saveHistoryAndLatest(name, history)
+}
+
+// Permanently removes the data of the versions, their metadata is kept and they are reported as destroyed
+func (ks *KV1Store) Destroy(name string, versions []int) error {
+	history, err := ks.history(name)
+	if err != nil {
+		return err
+	}
+	if history.CurrentVersion == 0 {
+		return errors.New("secret not found")
+	}
+	err = ks.adopt(name, &history)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		metadata, ok := history.Versions[strconv.Itoa(version)]
+		if !ok {
+			continue
+		}
+		err = ks.Vault.DeleteV1(kv1VersionPath(name, version))
+		if err != nil {
+			return err
+		}
+		metadata.Destroyed = true
+		history.Versions[strconv.Itoa(version)] = metadata
+	}
+	return ks.saveHistoryAndLatest(name, history)
+}
+
+// A secret that doesn't exist at all has a CurrentVersion of 0
+func (ks *KV1Store) history(name string) (kv1History, error) {
+	history := kv1History{
+		Versions: map[string]kv1VersionMetadata{},
+	}
+
+	if !ks.Vault.ExistsV1(kv1MetadataPath(name)) {
+		if ks.Vault.ExistsV1(name) {
+			history.CurrentVersion = 1
+			history.Versions["1"] = kv1VersionMetadata{}
+			history.legacy = true
+		}
+		return history, nil
+	}
+
+	metadata, err := ks.Vault.GetV1(kv1MetadataPath(name))
+	if err != nil {
+		return history, err
+	}
+	metadataJson, err := json.Marshal(metadata)
+	if err != nil {
+		return history, err
+	}
+	err = json.Unmarshal(metadataJson, &history)
+	if err != nil {
+		return history, errors.New(fmt.Sprintf("version history of %s is malformed: %s", name, err))
+	}
+	if history.Versions == nil {
+		history.Versions = map[string]kv1VersionMetadata{}
+	}
+	return history, nil
+}
+
+func (ks *KV1Store) saveHistory(name string, history kv1History) error {
+	historyJson, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	var metadata map[string]interface{}
+	err = json.Unmarshal(historyJson, &metadata)
+	if err != nil {
+		return err
+	}
+	return ks.Vault.SetV1(kv1MetadataPath(name), metadata)
+}
+
+// Saves the history and keeps the secret's own path in sync with it, like a KV2 read the path only has a value while
+// the current version is live
+func (ks *KV1Store) saveHistoryAndLatest(name string, history kv1History) error {
+	err := ks.saveHistory(name, history)
+	if err != nil {
+		return err
+	}
+
+	if !history.live(history.CurrentVersion) {
+		return ks.Vault.DeleteV1(name)
+	}
+	latest, err := ks.Vault.GetV1(kv1VersionPath(name, history.CurrentVersion))
+	if err != nil {
+		return err
+	}
+	return ks.Vault.SetV1(name, latest)
+}
+
+// Copies a secret without history into the history as version 1 so it can be versioned like any other secret
+func (ks *KV1Store) adopt(name string, history *kv1History) error {
+	if !history.legacy {
+		return nil
+	}
+
+	value, err := ks.Vault.GetV1(name)
+	if err != nil {
+		return err
+	}
+	err = ks.Vault.SetV1(kv1VersionPath(name, 1), value)
+	if err != nil {
+		return err
+	}
+	history.legacy = false
+	return nil
+}
+
+func (ks *KV1Store) getVersion(name string, version int, history kv1History) (secret.Secret, error) {
+	path := kv1VersionPath(name, version)
+	if history.legacy {
+		path = name
+	}
+
+	value, err := ks.Vault.GetV1(path)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+
+	id, err := EncodeId(VersionedSecretMetaData{
+		Name:    name,
+		Version: json.Number(strconv.Itoa(version)),
+	})
+	if err != nil {
+		return secret.Secret{}, err
+	}
+
+	return secret.Secret{
+		Id:    id,
+		Name:  name,
+		Value: value,
+	}, nil
+}
+
+func kv1MetadataPath(name string) string {
+	return fmt.Sprintf("%s/metadata/%s", KV1HistoryPrefix, strings.TrimPrefix(name, "/"))
+}
+
+func kv1VersionPath(name string, version int) string {
+	return fmt.Sprintf("%s/versions/%s/%d", KV1HistoryPrefix, strings.TrimPrefix(name, "/"), version)
+}
+
+// The credential a name under KV1HistoryPrefix keeps the history of, false for any other name
+func KV1HistoryCredentialName(name string) (string, bool) {
+	name = "/" + strings.TrimPrefix(name, "/")
+	metadataPrefix := KV1HistoryPrefix + "/metadata/"
+	versionsPrefix := KV1HistoryPrefix + "/versions/"
+	switch {
+	case strings.HasPrefix(name, metadataPrefix):
+		return "/" + strings.TrimPrefix(name, metadataPrefix), true
+	case strings.HasPrefix(name, versionsPrefix):
+		versionName := "/" + strings.TrimPrefix(name, versionsPrefix)
+		// the last segment is the version number
+		if end := strings.LastIndex(versionName, "/"); end > 0 {
+			return versionName[:end], true
+		}
+		return versionName, true
+	}
+	return "", false
+}
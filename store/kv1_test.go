@@ -0,0 +1,139 @@
+package store_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KV1 Store", func() {
+	kv1Id := func(name string, version string) string {
+		id, err := store.EncodeId(store.VersionedSecretMetaData{Name: name, Version: json.Number(version)})
+		Expect(err).ToNot(HaveOccurred())
+		return id
+	}
+
+	It("detects the kv version of the mount", func() {
+		kvVersion, err := healthyKV1Store.Vault.KVVersion()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kvVersion).To(Equal(vault.KVVersion1))
+
+		kvVersion, err = healthySimpleStore.Vault.KVVersion()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kvVersion).To(Equal(vault.KVVersion2))
+	})
+
+	It("versions secrets and keeps the latest value at the secret's path", func() {
+		name := "/kv1/password"
+		firstId, err := healthyKV1Store.Set(name, map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = healthyKV1Store.Set(name, map[string]interface{}{"value": "second"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(healthyKV1Store.Exists(name)).To(BeTrue())
+		raw, err := healthyKV1Store.Vault.GetV1(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(raw).To(Equal(map[string]interface{}{"value": "second"}))
+
+		secrets, err := healthyKV1Store.GetByName(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secrets).To(HaveLen(2))
+		Expect(secrets[0].Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+		first, err := healthyKV1Store.GetById(firstId)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Value).To(Equal(map[string]interface{}{"value": "first"}))
+
+		latest, err := healthyKV1Store.GetById(kv1Id(name, "0"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+	})
+
+	It("treats secrets written without bosh-vault as version 1", func() {
+		name := "/kv1/preexisting"
+		Expect(healthyKV1Store.Vault.SetV1(name, map[string]interface{}{"value": "old"})).To(Succeed())
+
+		latest, err := healthyKV1Store.GetLatestByName(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Id).To(Equal(kv1Id(name, "1")))
+
+		id, err := healthyKV1Store.Set(name, map[string]interface{}{"value": "new"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(id).To(Equal(kv1Id(name, "2")))
+
+		first, err := healthyKV1Store.GetById(latest.Id)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Value).To(Equal(map[string]interface{}{"value": "old"}))
+	})
+
+	It("deletes, undeletes, and destroys versions", func() {
+		name := "/kv1/versions"
+		_, err := healthyKV1Store.Set(name, map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = healthyKV1Store.Set(name, map[string]interface{}{"value": "second"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(healthyKV1Store.DeleteByName(name)).To(Succeed())
+		Expect(healthyKV1Store.Exists(name)).To(BeFalse())
+		Expect(healthyKV1Store.DeleteByName(name)).ToNot(Succeed())
+
+		versions, err := healthyKV1Store.GetVersions(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[0].Version).To(Equal(2))
+		Expect(versions[0].DeletionTime).ToNot(BeEmpty())
+		Expect(versions[1].DeletionTime).ToNot(BeEmpty())
+
+		Expect(healthyKV1Store.Undelete(name, []int{2})).To(Succeed())
+		Expect(healthyKV1Store.Exists(name)).To(BeTrue())
+		latest, err := healthyKV1Store.GetLatestByName(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+		Expect(healthyKV1Store.Destroy(name, []int{2})).To(Succeed())
+		Expect(healthyKV1Store.Exists(name)).To(BeFalse())
+		versions, err = healthyKV1Store.GetVersions(name)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions[0].Destroyed).To(BeTrue())
+	})
+
+	It("removes the secret and its history with metadata-destroy", func() {
+		name := "/kv1/metadata-destroy"
+		policyStore := healthyKV1Store
+		policyStore.Vault.Config.DeletePolicy = config.DeletePolicyMetadataDestroy
+
+		_, err := policyStore.Set(name, map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(policyStore.DeleteByName(name)).To(Succeed())
+
+		_, err = policyStore.GetVersions(name)
+		Expect(err).To(HaveOccurred())
+		Expect(policyStore.Exists(name)).To(BeFalse())
+	})
+
+	It("lists secrets without the version history", func() {
+		_, err := healthyKV1Store.Set("/kv1-listing/deployment/password", map[string]interface{}{"value": "listed"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = healthyKV1Store.Set("/kv1-listing/deployment/nested/certificate", map[string]interface{}{"value": "listed"})
+		Expect(err).ToNot(HaveOccurred())
+
+		names, err := healthyKV1Store.List("/kv1-listing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(ConsistOf("/kv1-listing/deployment/password", "/kv1-listing/deployment/nested/certificate"))
+
+		names, err = healthyKV1Store.List("/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(ContainElement("/kv1-listing/deployment/password"))
+		for _, name := range names {
+			Expect(name).ToNot(HavePrefix(store.KV1HistoryPrefix))
+		}
+	})
+
+	It("rejects values that aren't objects", func() {
+		_, err := healthyKV1Store.Set("/kv1/string", "just a string")
+		Expect(err).To(HaveOccurred())
+	})
+})
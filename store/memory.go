@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"sync"
+)
+
+// Keeps secrets in memory with the same versioning, soft deleting, destroying, and ids as a KV2 mount. It's safe to
+// use from multiple goroutines and meant for tests, here and in other projects, that shouldn't need a Vault.
+type MemoryStore struct {
+	secrets *versionedSecrets
+
+	mutex   sync.RWMutex
+	healthy bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		secrets: newVersionedSecrets(config.DefaultVaultDeletePolicy),
+		healthy: true,
+	}
+}
+
+// Changes how DeleteByName treats versions, one of the config.DeletePolicy constants
+func (ms *MemoryStore) SetDeletePolicy(deletePolicy string) {
+	ms.secrets.mutex.Lock()
+	defer ms.secrets.mutex.Unlock()
+	ms.secrets.deletePolicy = deletePolicy
+}
+
+// Makes the store report itself as unhealthy, e.g. to test how an unreachable or sealed Vault is handled. Only Healthy
+// and Status are affected, reads and writes keep working.
+func (ms *MemoryStore) SetHealthy(healthy bool) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.healthy = healthy
+}
+
+func (ms *MemoryStore) Healthy() bool {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+	return ms.healthy
+}
+
+func (ms *MemoryStore) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"memory": map[string]interface{}{
+			"healthy": ms.Healthy(),
+			"secrets": ms.secrets.count(),
+		},
+	}
+}
+
+func (ms *MemoryStore) Close() {}
+
+func (ms *MemoryStore) Exists(name string) bool {
+	return ms.secrets.exists(name)
+}
+
+func (ms *MemoryStore) GetLatestByName(name string) (secret.Secret, error) {
+	secrets, err := ms.GetByName(name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	if len(secrets) == 0 {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+	return secrets[0], nil
+}
+
+func (ms *MemoryStore) GetByName(name string) ([]secret.Secret, error) {
+	return ms.secrets.getByName(name)
+}
+
+func (ms *MemoryStore) GetById(id string) (secret.Secret, error) {
+	return ms.secrets.getById(id)
+}
+
+func (ms *MemoryStore) Set(name string, value interface{}) (string, error) {
+	return ms.secrets.set(name, value)
+}
+
+func (ms *MemoryStore) DeleteByName(name string) error {
+	return ms.secrets.deleteByName(name)
+}
+
+func (ms *MemoryStore) List(prefix string) ([]string, error) {
+	return ms.secrets.list(prefix), nil
+}
+
+func (ms *MemoryStore) GetVersions(name string) ([]secret.Version, error) {
+	return ms.secrets.getVersions(name)
+}
+
+func (ms *MemoryStore) Undelete(name string, versions []int) error {
+	return ms.secrets.undelete(name, versions)
+}
+
+func (ms *MemoryStore) Destroy(name string, versions []int) error {
+	return ms.secrets.destroy(name, versions)
+}
@@ -0,0 +1,98 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Store", func() {
+	var memoryStore *store.MemoryStore
+
+	BeforeEach(func() {
+		memoryStore = store.NewMemoryStore()
+	})
+
+	It("can toggle its health", func() {
+		Expect(memoryStore.Healthy()).To(BeTrue())
+		memoryStore.SetHealthy(false)
+		Expect(memoryStore.Healthy()).To(BeFalse())
+		Expect(memoryStore.Status()).To(HaveKeyWithValue("memory", HaveKeyWithValue("healthy", false)))
+	})
+
+	It("versions secrets like KV2", func() {
+		firstId, err := memoryStore.Set("some_password", map[string]interface{}{"value": "first"})
+		Expect(err).ToNot(HaveOccurred())
+		// the same id the vault backend returns for the first version
+		Expect(firstId).To(Equal("eyJuYW1lIjoic29tZV9wYXNzd29yZCIsInZlcnNpb24iOjF9"))
+		_, err = memoryStore.Set("/some_password", map[string]interface{}{"value": "second"})
+		Expect(err).ToNot(HaveOccurred())
+
+		latest, err := memoryStore.GetLatestByName("some_password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+		first, err := memoryStore.GetById(firstId)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Value).To(Equal(map[string]interface{}{"value": "first"}))
+	})
+
+	It("returns copies of values", func() {
+		value := map[string]interface{}{"value": "original"}
+		id, err := memoryStore.Set("/copied", value)
+		Expect(err).ToNot(HaveOccurred())
+		value["value"] = "changed after set"
+
+		stored, err := memoryStore.GetById(id)
+		Expect(err).ToNot(HaveOccurred())
+		stored.Value.(map[string]interface{})["value"] = "changed after get"
+
+		stored, err = memoryStore.GetById(id)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored.Value).To(Equal(map[string]interface{}{"value": "original"}))
+	})
+
+	It("only soft deletes the latest version with latest-soft", func() {
+		memoryStore.SetDeletePolicy(config.DeletePolicyLatestSoft)
+		_, _ = memoryStore.Set("/latest-soft", map[string]interface{}{"value": "first"})
+		_, _ = memoryStore.Set("/latest-soft", map[string]interface{}{"value": "second"})
+
+		Expect(memoryStore.DeleteByName("/latest-soft")).To(Succeed())
+		Expect(memoryStore.Exists("/latest-soft")).To(BeFalse())
+		Expect(memoryStore.DeleteByName("/latest-soft")).ToNot(Succeed())
+
+		secrets, err := memoryStore.GetByName("/latest-soft")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secrets).To(HaveLen(1))
+		Expect(secrets[0].Value).To(Equal(map[string]interface{}{"value": "first"}))
+	})
+
+	It("is safe to use concurrently", func() {
+		var waitGroup sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			waitGroup.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer waitGroup.Done()
+				for j := 0; j < 10; j++ {
+					_, err := memoryStore.Set("/concurrent", map[string]interface{}{"value": fmt.Sprintf("%d-%d", i, j)})
+					Expect(err).ToNot(HaveOccurred())
+					_, err = memoryStore.GetLatestByName("/concurrent")
+					Expect(err).ToNot(HaveOccurred())
+					_, err = memoryStore.List("/")
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}(i)
+		}
+		waitGroup.Wait()
+
+		versions, err := memoryStore.GetVersions("/concurrent")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(100))
+		Expect(versions[0].Version).To(Equal(100))
+	})
+})
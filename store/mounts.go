@@ -0,0 +1,98 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+)
+
+// A misconfigured mount would otherwise only show up as "secret not found" on every request, mounts are checked on
+// startup instead. When the token isn't allowed to look up mounts the check is skipped, the mount may still work. Any
+// other error means Vault can't be used at all and fails the startup.
+
+// Returns the KV version of the default Vault's mount, creating a KV2 mount if it is missing and createmount is set.
+// The configured kvversion is only needed when the token isn't allowed to look the mount up.
+func checkDefaultMount(v *vault.Vault) (int, error) {
+	if v.Config.KVVersion != 0 && v.Config.KVVersion != vault.KVVersion1 && v.Config.KVVersion != vault.KVVersion2 {
+		return 0, errors.New(fmt.Sprintf("kvversion must be %d or %d, not %d", vault.KVVersion1, vault.KVVersion2, v.Config.KVVersion))
+	}
+
+	mount, err := v.GetMount(v.Config.Mount)
+	if vault.IsPermissionDenied(err) {
+		if v.Config.KVVersion == 0 {
+			return 0, errors.New(fmt.Sprintf("not allowed to check the %s mount on %s, set kvversion to the version of the mount: %s", v.Config.Mount, v.Config.Address, err))
+		}
+		logger.Log.Infof("not allowed to check the %s mount on %s, using the configured kvversion %d: %s", v.Config.Mount, v.Config.Address, v.Config.KVVersion, err)
+		return v.Config.KVVersion, nil
+	}
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("could not check the %s mount on %s: %s", v.Config.Mount, v.Config.Address, err))
+	}
+
+	if mount == nil {
+		if !v.Config.CreateMount {
+			return 0, errors.New(fmt.Sprintf("no mount found at %s on %s, enable a kv2 secret engine there or set createmount", v.Config.Mount, v.Config.Address))
+		}
+		logger.Log.Infof("creating kv2 mount %s on %s", v.Config.Mount, v.Config.Address)
+		err = v.CreateKV2Mount()
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("could not create kv2 mount %s on %s: %s", v.Config.Mount, v.Config.Address, err))
+		}
+		return vault.KVVersion2, nil
+	}
+
+	if mount.KVVersion == 0 {
+		return 0, errors.New(fmt.Sprintf("%s on %s is a %s mount, bosh-vault needs a kv mount", mount.Path, v.Config.Address, mount.Type))
+	}
+	if v.Config.KVVersion != 0 && v.Config.KVVersion != mount.KVVersion {
+		return 0, errors.New(fmt.Sprintf("kvversion is %d but %s on %s is a kv%d mount", v.Config.KVVersion, mount.Path, v.Config.Address, mount.KVVersion))
+	}
+	return mount.KVVersion, nil
+}
+
+// Upstream redirects read through the redirect Vault's KV2 mount, v1 redirects read their path from a KV1 mount, and
+// dynamic redirects read their path from any secret engine. Rules with a namespace are checked in the child namespace.
+func checkRedirectMount(redirectType string, v *vault.Vault, redirect string, namespace string) error {
+	path := v.Config.Mount
+	if redirectType == v1Redirect || redirectType == dynamicRedirect {
+		path = redirect
+	}
+
+	mount, err := v.GetMount(path)
+	if vault.IsPermissionDenied(err) && namespace != "" {
+		// the token may not have access to the child namespace at all, in which case every redirected read is denied
+		logger.Log.Errorf("not allowed to check the mount of %s in namespace %s on redirect Vault %s, redirected reads will fail unless the token can access the namespace: %s", path, namespace, v.Config.Address, err)
+		return nil
+	}
+	if vault.IsPermissionDenied(err) {
+		logger.Log.Errorf("not allowed to check the mount of %s on redirect Vault %s: %s", path, v.Config.Address, err)
+		return nil
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("could not check the mount of %s on redirect Vault %s: %s", path, v.Config.Address, err))
+	}
+	if mount == nil {
+		return errors.New(fmt.Sprintf("no mount found for %s redirect %s on %s", redirectType, path, v.Config.Address))
+	}
+
+	switch redirectType {
+	case v1Redirect:
+		if mount.KVVersion != vault.KVVersion1 {
+			return errors.New(fmt.Sprintf("v1 redirects need a kv1 mount but %s on %s is in the %s mount %s", redirect, v.Config.Address, mountKind(mount), mount.Path))
+		}
+	case dynamicRedirect:
+	default:
+		if mount.KVVersion != vault.KVVersion2 {
+			return errors.New(fmt.Sprintf("upstream redirects need a kv2 mount but %s on %s is a %s mount", mount.Path, v.Config.Address, mountKind(mount)))
+		}
+	}
+	return nil
+}
+
+func mountKind(mount *vault.Mount) string {
+	if mount.KVVersion != 0 {
+		return fmt.Sprintf("kv%d", mount.KVVersion)
+	}
+	return mount.Type
+}
@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mount checks", func() {
+	var deniedMountsVault *httptest.Server
+
+	BeforeEach(func() {
+		// a token that may use the mount but can't look up mounts
+		deniedMountsVault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/v1/sys/mounts") || strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	})
+
+	AfterEach(func() {
+		deniedMountsVault.Close()
+	})
+
+	deniedMountsConfig := func(kvVersion int) config.Configuration {
+		var bvConfig config.Configuration
+		bvConfig.Vault = config.VaultConfiguration{
+			Address:   deniedMountsVault.URL,
+			Token:     "token",
+			Mount:     "config-server",
+			KVVersion: kvVersion,
+		}
+		return bvConfig
+	}
+
+	It("refuses to guess the kv version when the mount can't be looked up", func() {
+		_, err := store.NewStore(deniedMountsConfig(0))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("kvversion"))
+	})
+
+	It("uses the configured kv version when the mount can't be looked up", func() {
+		secretStore, err := store.NewStore(deniedMountsConfig(1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secretStore).To(BeAssignableToTypeOf(&store.KV1Store{}))
+		secretStore.Close()
+
+		secretStore, err = store.NewStore(deniedMountsConfig(2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secretStore).To(BeAssignableToTypeOf(&store.SimpleStore{}))
+		secretStore.Close()
+	})
+
+	It("rejects unknown kv versions", func() {
+		_, err := store.NewStore(deniedMountsConfig(3))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects a kv version that doesn't match the mount", func() {
+		kv1Config := healthyKV1Store.Vault.Config
+		kv1Config.KVVersion = 2
+		var bvConfig config.Configuration
+		bvConfig.Vault = kv1Config
+		_, err := store.NewStore(bvConfig)
+		Expect(err).To(HaveOccurred())
+	})
+})
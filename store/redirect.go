@@ -61,6 +61,24 @@ func (rs *RedirectStore) Healthy() bool {
 	return rs.DefaultVault.Healthy()
 }
 
+func (rs *RedirectStore) Status() map[string]interface{} {
+	redirectStatuses := make([]vault.Status, 0)
+	for i := range rs.Vaults {
+		redirectStatuses = append(redirectStatuses, rs.Vaults[i].Status())
+	}
+	return map[string]interface{}{
+		"vault":     rs.DefaultVault.Status(),
+		"redirects": redirectStatuses,
+	}
+}
+
+func (rs *RedirectStore) Close() {
+	rs.DefaultVault.Close()
+	for i := range rs.Vaults {
+		rs.Vaults[i].Close()
+	}
+}
+
 func (rs *RedirectStore) Exists(name string) bool {
 	// assumption is that EXISTENCE always refers to the expected location and default Vault
 	return rs.DefaultVault.Exists(name)
@@ -71,6 +89,9 @@ func (rs *RedirectStore) GetLatestByName(name string) (secret.Secret, error) {
 	if err != nil {
 		return secret.Secret{}, err
 	}
+	if len(secrets) == 0 {
+		return secret.Secret{}, errors.New("secret not found")
+	}
 	return secrets[0], nil
 }
 
@@ -171,3 +192,21 @@ func (rs *RedirectStore) Set(name string, value interface{}) (string, error) {
 func (rs *RedirectStore) DeleteByName(name string) error {
 	return deleteByName(&rs.DefaultVault, name)
 }
+
+// redirects only apply to lookups by name or id, everything they have served is cached in the default Vault
+func (rs *RedirectStore) List(prefix string) ([]string, error) {
+	return listNames(&rs.DefaultVault, prefix)
+}
+
+// version history, undelete, and destroy only ever apply to the default Vault, redirected Vaults are read only
+func (rs *RedirectStore) GetVersions(name string) ([]secret.Version, error) {
+	return getVersions(&rs.DefaultVault, name)
+}
+
+func (rs *RedirectStore) Undelete(name string, versions []int) error {
+	return rs.DefaultVault.Undelete(name, versions)
+}
+
+func (rs *RedirectStore) Destroy(name string, versions []int) error {
+	return rs.DefaultVault.Destroy(name, versions)
+}
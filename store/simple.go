@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
 	"github.com/cloudfoundry-community/bosh-vault/vault"
 )
@@ -13,6 +14,16 @@ func (vs *SimpleStore) Healthy() bool {
 	return vs.Vault.Healthy()
 }
 
+func (vs *SimpleStore) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"vault": vs.Vault.Status(),
+	}
+}
+
+func (vs *SimpleStore) Close() {
+	vs.Vault.Close()
+}
+
 func (vs *SimpleStore) Exists(name string) bool {
 	return vs.Vault.Exists(name)
 }
@@ -22,6 +33,9 @@ func (vs *SimpleStore) GetLatestByName(name string) (secret.Secret, error) {
 	if err != nil {
 		return secret.Secret{}, err
 	}
+	if len(secrets) == 0 {
+		return secret.Secret{}, errors.New("secret not found")
+	}
 	return secrets[0], nil
 }
 
@@ -40,3 +54,19 @@ func (vs *SimpleStore) Set(name string, value interface{}) (string, error) {
 func (vs *SimpleStore) DeleteByName(name string) error {
 	return deleteByName(&vs.Vault, name)
 }
+
+func (vs *SimpleStore) List(prefix string) ([]string, error) {
+	return listNames(&vs.Vault, prefix)
+}
+
+func (vs *SimpleStore) GetVersions(name string) ([]secret.Version, error) {
+	return getVersions(&vs.Vault, name)
+}
+
+func (vs *SimpleStore) Undelete(name string, versions []int) error {
+	return vs.Vault.Undelete(name, versions)
+}
+
+func (vs *SimpleStore) Destroy(name string, versions []int) error {
+	return vs.Vault.Destroy(name, versions)
+}
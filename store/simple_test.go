@@ -1,13 +1,15 @@
 package store_test
 
 import (
-	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/store/storetest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
-var seedData = []store.VaultTestData{
-	store.VaultTestData{
+var seedData = []storetest.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_password",
 		Value: map[string]interface{}{
 			"value": "$$$$$wakawakawaka$$$$$",
@@ -15,7 +17,7 @@ var seedData = []store.VaultTestData{
 		Id:   "eyJuYW1lIjoic29tZV9wYXNzd29yZCIsInZlcnNpb24iOjF9", // ID expects version 1
 		Seed: false,                                              // A test expects to write this in for the first time
 	},
-	store.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_value",
 		Value: map[string]interface{}{
 			"value": "theBestValue",
@@ -130,6 +132,89 @@ var _ = Describe("Simple Store", func() {
 				}
 			})
 
+			It("can list secrets under a path prefix", func() {
+				_, err := healthySimpleStore.Set("/listing/deployment/password", map[string]interface{}{"value": "listed"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = healthySimpleStore.Set("/listing/deployment/nested/certificate", map[string]interface{}{"value": "listed"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = healthySimpleStore.Set("/listing_sibling/password", map[string]interface{}{"value": "listed"})
+				Expect(err).ToNot(HaveOccurred())
+
+				names, err := healthySimpleStore.List("/listing")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(names).To(ConsistOf("/listing/deployment/password", "/listing/deployment/nested/certificate"))
+
+				names, err = healthySimpleStore.List("/does/not/exist")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(names).To(BeEmpty())
+			})
+
+			It("can report, undelete, and destroy deleted versions", func() {
+				name := "/versions/password"
+				_, err := healthySimpleStore.Set(name, map[string]interface{}{"value": "first"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = healthySimpleStore.Set(name, map[string]interface{}{"value": "second"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(healthySimpleStore.DeleteByName(name)).To(Succeed())
+
+				versions, err := healthySimpleStore.GetVersions(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(versions).To(HaveLen(2))
+				Expect(versions[0].Version).To(Equal(2))
+				Expect(versions[0].DeletionTime).ToNot(BeEmpty())
+				Expect(versions[1].DeletionTime).ToNot(BeEmpty())
+
+				Expect(healthySimpleStore.Undelete(name, []int{2})).To(Succeed())
+				latest, err := healthySimpleStore.GetLatestByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(latest.Value).To(Equal(map[string]interface{}{"value": "second"}))
+
+				Expect(healthySimpleStore.Destroy(name, []int{1})).To(Succeed())
+				versions, err = healthySimpleStore.GetVersions(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(versions[1].Destroyed).To(BeTrue())
+			})
+
+			Context("delete policies", func() {
+				deleteWithPolicy := func(name string, deletePolicy string) []secret.Version {
+					policyStore := healthySimpleStore
+					policyStore.Vault.Config.DeletePolicy = deletePolicy
+
+					_, err := policyStore.Set(name, map[string]interface{}{"value": "first"})
+					Expect(err).ToNot(HaveOccurred())
+					_, err = policyStore.Set(name, map[string]interface{}{"value": "second"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(policyStore.DeleteByName(name)).To(Succeed())
+
+					// nothing is left to delete
+					Expect(policyStore.DeleteByName(name)).ToNot(Succeed())
+
+					versions, _ := policyStore.GetVersions(name)
+					return versions
+				}
+
+				It("only soft deletes the latest version with latest-soft", func() {
+					versions := deleteWithPolicy("/delete-policy/latest-soft", config.DeletePolicyLatestSoft)
+					Expect(versions).To(HaveLen(2))
+					Expect(versions[0].DeletionTime).ToNot(BeEmpty())
+					Expect(versions[1].DeletionTime).To(BeEmpty())
+				})
+
+				It("soft deletes every version with all-soft", func() {
+					versions := deleteWithPolicy("/delete-policy/all-soft", config.DeletePolicyAllSoft)
+					Expect(versions).To(HaveLen(2))
+					Expect(versions[0].DeletionTime).ToNot(BeEmpty())
+					Expect(versions[1].DeletionTime).ToNot(BeEmpty())
+				})
+
+				It("removes every version with metadata-destroy", func() {
+					versions := deleteWithPolicy("/delete-policy/metadata-destroy", config.DeletePolicyMetadataDestroy)
+					Expect(versions).To(BeEmpty())
+					_, err := healthySimpleStore.GetByName("/delete-policy/metadata-destroy")
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
 		})
 	})
 })
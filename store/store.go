@@ -8,14 +8,45 @@ import (
 	"github.com/cloudfoundry-community/bosh-vault/logger"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
 	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"sort"
 	"strconv"
+	"strings"
 )
 
-func GetStore(bvConfig config.Configuration) secret.Store {
+// The vault backend, with redirects and transit encryption when they're configured
+func getVaultStore(bvConfig config.Configuration) (secret.Store, error) {
 	defaultVault, err := vault.GetVault(bvConfig.Vault)
 	if err != nil {
+		defaultVault.Close()
 		// if we can't connect to the default backend that's a fatal error
-		logger.Log.Fatalf("could not communicate with default backend Vault server at %s, %s", bvConfig.Vault.Address, err)
+		return nil, errors.New(fmt.Sprintf("could not communicate with default backend Vault server at %s, %s", bvConfig.Vault.Address, err))
+	}
+
+	secretStore, err := getKVStore(bvConfig, defaultVault)
+	if err != nil {
+		defaultVault.Close()
+		return nil, err
+	}
+	if bvConfig.Store.Transit.Key == "" {
+		return secretStore, nil
+	}
+	logger.Log.Infof("encrypting credentials with transit key %s of the %s mount on %s", bvConfig.Store.Transit.Key, bvConfig.Store.Transit.Mount, bvConfig.Vault.Address)
+	return NewTransitStore(secretStore, &defaultVault, bvConfig.Store.Transit)
+}
+
+func getKVStore(bvConfig config.Configuration, defaultVault vault.Vault) (secret.Store, error) {
+	kvVersion, err := checkDefaultMount(&defaultVault)
+	if err != nil {
+		return nil, err
+	}
+
+	if kvVersion == vault.KVVersion1 {
+		// redirected secrets are cached in the default Vault with the KV2 helpers
+		if len(bvConfig.Redirects) > 0 {
+			return nil, errors.New(fmt.Sprintf("redirects require a kv2 mount but %s on %s is a kv1 mount", bvConfig.Vault.Mount, bvConfig.Vault.Address))
+		}
+		logger.Log.Infof("using kv1 mount %s on %s, versions are emulated under %s", bvConfig.Vault.Mount, bvConfig.Vault.Address, KV1HistoryPrefix)
+		return &KV1Store{Vault: defaultVault}, nil
 	}
 
 	if len(bvConfig.Redirects) > 0 {
@@ -26,7 +57,9 @@ func GetStore(bvConfig config.Configuration) secret.Store {
 			v, err := vault.GetVault(redirectConfiguration.Vault)
 			store.Vaults = append(store.Vaults, v)
 			if err != nil {
-				logger.Log.Errorf("Error establishing a connection to %s for redirects", redirectConfiguration.Vault.Address)
+				// redirected reads would be denied until the login succeeds, which the health check doesn't report
+				store.Close()
+				return nil, errors.New(fmt.Sprintf("could not communicate with redirect Vault server at %s, %s", redirectConfiguration.Vault.Address, err))
 			}
 
 			for _, rules := range redirectConfiguration.Rules {
@@ -35,14 +68,27 @@ func GetStore(bvConfig config.Configuration) secret.Store {
 				redirect.Redirect = rules.Redirect
 				redirect.Type = redirectConfiguration.Type
 				redirect.Vault = &store.Vaults[redirectConfigIndex]
+				if rules.Namespace != "" {
+					namespacedVault := store.Vaults[redirectConfigIndex].InNamespace(rules.Namespace)
+					redirect.Vault = &namespacedVault
+					// v1 and dynamic redirects read the redirect path directly rather than through the KV2 mount
+					if redirect.Type == v1Redirect || redirect.Type == dynamicRedirect {
+						redirect.Redirect = fmt.Sprintf("%s/%s", strings.Trim(rules.Namespace, "/"), strings.TrimPrefix(rules.Redirect, "/"))
+					}
+				}
+				err = checkRedirectMount(redirect.Type, redirect.Vault, redirect.Redirect, rules.Namespace)
+				if err != nil {
+					store.Close()
+					return nil, err
+				}
 				store.Rules = append(store.Rules, redirect)
 			}
 		}
-		return &store
+		return &store, nil
 	} else {
 		var store SimpleStore
 		store.Vault = defaultVault
-		return &store
+		return &store, nil
 	}
 }
 
@@ -62,9 +108,13 @@ func getByName(v *vault.Vault, name string) ([]secret.Secret, error) {
 	versionCount := len(versionsRaw.(map[string]interface{}))
 
 	for i := versionCount; i > 0; i-- {
-		// Skip any destroyed secret versions
-		// todo: determine if we should also hide "soft deleted" secrets
-		if versionsRaw.(map[string]interface{})[strconv.Itoa(i)].(map[string]interface{})["destroyed"].(bool) == true {
+		// Only live versions are returned, like every other store. Soft deleted versions can be inspected and
+		// restored with getVersions and undelete
+		versionMetadata, _ := versionsRaw.(map[string]interface{})[strconv.Itoa(i)].(map[string]interface{})
+		if destroyed, _ := versionMetadata["destroyed"].(bool); destroyed {
+			continue
+		}
+		if deletionTime, _ := versionMetadata["deletion_time"].(string); deletionTime != "" {
 			continue
 		}
 		secretRequest := VersionedSecretMetaData{
@@ -83,6 +133,36 @@ func getByName(v *vault.Vault, name string) ([]secret.Secret, error) {
 	return secretVersions, err
 }
 
+// Recursively walks everything under the prefix and returns the full name of every secret found
+func listNames(v *vault.Vault, prefix string) ([]string, error) {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	names := make([]string, 0)
+	keys, err := v.List(prefix)
+	if err != nil {
+		return names, err
+	}
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			nestedNames, err := listNames(v, prefix+key)
+			if err != nil {
+				return names, err
+			}
+			names = append(names, nestedNames...)
+		} else {
+			names = append(names, prefix+key)
+		}
+	}
+
+	return names, nil
+}
+
 func getById(v *vault.Vault, id string) (secret.Secret, error) {
 	var response secret.Secret
 	decodedId, err := DecodeId(id)
@@ -106,8 +186,81 @@ func getById(v *vault.Vault, id string) (secret.Secret, error) {
 	return response, nil
 }
 
+// Every version of the secret newest first, including soft deleted and destroyed versions
+func getVersions(v *vault.Vault, name string) ([]secret.Version, error) {
+	versions := make([]secret.Version, 0)
+
+	metadata, err := v.GetMetadata(name)
+	if err != nil {
+		return versions, err
+	}
+
+	versionsRaw, ok := metadata["versions"].(map[string]interface{})
+	if !ok {
+		return versions, errors.New(fmt.Sprintf("Could not get version information for %s", name))
+	}
+
+	for versionKey, versionRaw := range versionsRaw {
+		versionNumber, err := strconv.Atoi(versionKey)
+		if err != nil {
+			continue
+		}
+		versionMetadata, _ := versionRaw.(map[string]interface{})
+
+		id, err := EncodeId(VersionedSecretMetaData{
+			Name:    name,
+			Version: json.Number(versionKey),
+		})
+		if err != nil {
+			return versions, err
+		}
+
+		version := secret.Version{
+			Id:      id,
+			Version: versionNumber,
+		}
+		version.CreatedTime, _ = versionMetadata["created_time"].(string)
+		version.DeletionTime, _ = versionMetadata["deletion_time"].(string)
+		version.Destroyed, _ = versionMetadata["destroyed"].(bool)
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// Deletes according to the Vault's delete policy, it's an error to delete a secret without any live versions
 func deleteByName(v *vault.Vault, name string) error {
-	return v.Delete(name)
+	versions, err := getVersions(v, name)
+	if err != nil {
+		return err
+	}
+
+	liveVersions := make([]int, 0)
+	for _, version := range versions {
+		if version.DeletionTime == "" && !version.Destroyed {
+			liveVersions = append(liveVersions, version.Version)
+		}
+	}
+	if len(liveVersions) == 0 {
+		return errors.New("secret not found")
+	}
+
+	switch v.Config.DeletePolicy {
+	case config.DeletePolicyLatestSoft:
+		// versions are newest first, an already deleted latest version means there is nothing to delete
+		if liveVersions[0] != versions[0].Version {
+			return errors.New("secret not found")
+		}
+		return v.Delete(name)
+	case config.DeletePolicyMetadataDestroy:
+		return v.DeleteMetadata(name)
+	default:
+		return v.DeleteVersions(name, liveVersions)
+	}
 }
 
 func setSecret(v *vault.Vault, name string, value interface{}) (string, error) {
@@ -1,8 +1,10 @@
 package store_test
 
 import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
 	"github.com/cloudfoundry-community/bosh-vault/logger"
 	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/store/storetest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
@@ -14,6 +16,8 @@ import (
 var healthySimpleStore store.SimpleStore
 var uninitializedVaultSimpleStore store.SimpleStore
 var sealedVaultSimpleStore store.SimpleStore
+var healthyKV1Store store.KV1Store
+var healthyTransitStore *store.TransitStore
 var storeListeners []net.Listener
 
 func TestStore(t *testing.T) {
@@ -33,19 +37,32 @@ func setupStores(t *testing.T) {
 	var err error
 
 	var listener1 net.Listener
-	healthySimpleStore, listener1, err = store.TestHealthySimpleStore(t)
+	healthySimpleStore, listener1, err = storetest.TestHealthySimpleStore(t)
 	Expect(err).NotTo(HaveOccurred())
 	storeListeners = append(storeListeners, listener1)
 
 	var listener2 net.Listener
-	sealedVaultSimpleStore, listener2, err = store.TestSealedSimpleStore(t)
+	sealedVaultSimpleStore, listener2, err = storetest.TestSealedSimpleStore(t)
 	Expect(err).NotTo(HaveOccurred())
 	storeListeners = append(storeListeners, listener2)
 
 	var listener3 net.Listener
-	uninitializedVaultSimpleStore, listener3, err = store.TestUninitializedVaultSimpleStore(t)
+	uninitializedVaultSimpleStore, listener3, err = storetest.TestUninitializedVaultSimpleStore(t)
 	Expect(err).NotTo(HaveOccurred())
 	storeListeners = append(storeListeners, listener3)
+
+	var listener4 net.Listener
+	healthyKV1Store, listener4, err = storetest.TestHealthyKV1Store(t)
+	Expect(err).NotTo(HaveOccurred())
+	storeListeners = append(storeListeners, listener4)
+
+	var listener5 net.Listener
+	healthyTransitStore, listener5, err = storetest.TestHealthyTransitStore(t, []config.TransitRule{
+		{Prefix: "/transit/encrypted", Types: []string{"password", "certificate"}},
+		{Prefix: "/transit/everything"},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	storeListeners = append(storeListeners, listener5)
 }
 
 var _ = AfterSuite(func() {
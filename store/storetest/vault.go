@@ -0,0 +1,143 @@
+// Vault servers for tests, kept out of the store package so the bosh-vault binary doesn't include the Vault core they need
+package storetest
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/transit"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	hashiVault "github.com/hashicorp/vault/vault"
+	"net"
+	"testing"
+)
+
+type VaultTestData struct {
+	Path  string
+	Value interface{}
+	Seed  bool
+	Id    string
+}
+
+func TestHealthySimpleStore(t *testing.T) (store.SimpleStore, net.Listener, error) {
+	core, _, token := hashiVault.TestCoreUnsealedWithConfig(t, &hashiVault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+	})
+
+	ln, addr := http.TestServer(t, core)
+	http.TestServerAuth(t, addr, token)
+	vc, err := vault.GetVault(config.VaultConfiguration{
+		Address: addr,
+		Token:   token,
+		Mount:   "config-server",
+	})
+	err = vc.Client.Sys().Mount("config-server", &api.MountInput{
+		Type:        "kv-v2",
+		Description: "some config server stuff",
+	})
+	vs := store.SimpleStore{
+		Vault: vc,
+	}
+	return vs, ln, err
+}
+
+func TestHealthyKV1Store(t *testing.T) (store.KV1Store, net.Listener, error) {
+	core, _, token := hashiVault.TestCoreUnsealedWithConfig(t, &hashiVault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+	})
+
+	ln, addr := http.TestServer(t, core)
+	http.TestServerAuth(t, addr, token)
+	vc, err := vault.GetVault(config.VaultConfiguration{
+		Address: addr,
+		Token:   token,
+		Mount:   "config-server-v1",
+	})
+	err = vc.Client.Sys().Mount("config-server-v1", &api.MountInput{
+		Type:        "kv",
+		Description: "some config server stuff on kv1",
+	})
+	ks := store.KV1Store{
+		Vault: vc,
+	}
+	return ks, ln, err
+}
+
+// A KV2 store wrapped with transit encryption using the bosh-vault key of the transit mount
+func TestHealthyTransitStore(t *testing.T, rules []config.TransitRule) (*store.TransitStore, net.Listener, error) {
+	core, _, token := hashiVault.TestCoreUnsealedWithConfig(t, &hashiVault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv":      kv.Factory,
+			"transit": transit.Factory,
+		},
+	})
+
+	ln, addr := http.TestServer(t, core)
+	http.TestServerAuth(t, addr, token)
+	vc, err := vault.GetVault(config.VaultConfiguration{
+		Address: addr,
+		Token:   token,
+		Mount:   "config-server",
+	})
+	if err != nil {
+		return nil, ln, err
+	}
+	err = vc.Client.Sys().Mount("config-server", &api.MountInput{
+		Type:        "kv-v2",
+		Description: "some config server stuff",
+	})
+	if err != nil {
+		return nil, ln, err
+	}
+	err = vc.Client.Sys().Mount("transit", &api.MountInput{
+		Type: "transit",
+	})
+	if err != nil {
+		return nil, ln, err
+	}
+	_, err = vc.Client.Logical().Write("transit/keys/bosh-vault", nil)
+	if err != nil {
+		return nil, ln, err
+	}
+
+	ts, err := store.NewTransitStore(&store.SimpleStore{Vault: vc}, &vc, config.TransitConfiguration{
+		Key:   "bosh-vault",
+		Rules: rules,
+	})
+	return ts, ln, err
+}
+
+func TestSealedSimpleStore(t *testing.T) (store.SimpleStore, net.Listener, error) {
+	core, _, token := hashiVault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	http.TestServerAuth(t, addr, token)
+	vc, err := vault.GetVault(config.VaultConfiguration{
+		Address: addr,
+		Token:   token,
+	})
+	_ = vc.Client.Sys().Seal()
+	vs := store.SimpleStore{
+		Vault: vc,
+	}
+	return vs, ln, err
+}
+
+func TestUninitializedVaultSimpleStore(t *testing.T) (store.SimpleStore, net.Listener, error) {
+	core := hashiVault.TestCore(t)
+	ln, addr := http.TestServer(t, core)
+	vc, err := vault.GetVault(config.VaultConfiguration{
+		Address: addr,
+		Token:   "",
+	})
+	vs := store.SimpleStore{
+		Vault: vc,
+	}
+	return vs, ln, err
+}
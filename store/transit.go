@@ -0,0 +1,285 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"reflect"
+	"strings"
+)
+
+// The fields an encrypted credential is stored with in place of its value
+const TransitKeyField = "bosh_vault_transit_key"
+const TransitCiphertextField = "bosh_vault_transit_ciphertext"
+
+// Wraps another store, the values of credentials matching the transit rules are encrypted with a transit key before
+// they're set and decrypted again when they're read. Values stored without encryption are read as they are, so rules
+// can be added to an existing store and changing the key only affects new versions.
+type TransitStore struct {
+	secret.Store
+	Vault  *vault.Vault
+	Config config.TransitConfiguration
+}
+
+func NewTransitStore(secretStore secret.Store, v *vault.Vault, transitConfig config.TransitConfiguration) (*TransitStore, error) {
+	if transitConfig.Key == "" {
+		return nil, errors.New("a transit key is required to encrypt credentials")
+	}
+	if transitConfig.Mount == "" {
+		transitConfig.Mount = config.DefaultTransitMount
+	}
+	return &TransitStore{
+		Store:  secretStore,
+		Vault:  v,
+		Config: transitConfig,
+	}, nil
+}
+
+func (ts *TransitStore) GetLatestByName(name string) (secret.Secret, error) {
+	latest, err := ts.Store.GetLatestByName(name)
+	if err != nil {
+		return latest, err
+	}
+	return ts.decrypt(latest)
+}
+
+func (ts *TransitStore) GetByName(name string) ([]secret.Secret, error) {
+	secrets, err := ts.Store.GetByName(name)
+	if err != nil {
+		return secrets, err
+	}
+	for i, s := range secrets {
+		secrets[i], err = ts.decrypt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+func (ts *TransitStore) GetById(id string) (secret.Secret, error) {
+	s, err := ts.Store.GetById(id)
+	if err != nil {
+		return s, err
+	}
+	return ts.decrypt(s)
+}
+
+func (ts *TransitStore) Set(name string, value interface{}) (string, error) {
+	if !ts.Encrypts(name, value) {
+		return ts.Store.Set(name, value)
+	}
+
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := ts.Vault.TransitEncrypt(ts.Config.Mount, ts.Config.Key, plaintext)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("could not encrypt %s with transit key %s: %s", name, ts.Config.Key, err))
+	}
+	return ts.Store.Set(name, map[string]interface{}{
+		TransitKeyField:        ts.Config.Key,
+		TransitCiphertextField: ciphertext,
+	})
+}
+
+// Whether a value set for the credential would be encrypted
+func (ts *TransitStore) Encrypts(name string, value interface{}) bool {
+	if len(ts.Config.Rules) == 0 {
+		return true
+	}
+
+	name = "/" + strings.TrimPrefix(name, "/")
+	valueTypes := credentialTypes(value)
+	for _, rule := range ts.Config.Rules {
+		if !strings.HasPrefix(name, "/"+strings.TrimPrefix(rule.Prefix, "/")) {
+			continue
+		}
+		if len(rule.Types) == 0 {
+			return true
+		}
+		for _, ruleType := range rule.Types {
+			for _, valueType := range valueTypes {
+				if ruleType == valueType {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+type RewrapResult struct {
+	// credentials whose latest version was rewrapped into a new version
+	Rewrapped []string `json:"rewrapped"`
+	// the oldest key version each key is still needed for by any readable version, the highest min_decryption_version
+	// that can be set without losing access to a version
+	MinKeyVersions map[string]int `json:"min_key_versions"`
+}
+
+// Re-encrypts the latest version of every encrypted credential under the prefix that isn't using the latest version of
+// its key, run after rotating the key. KV2 versions can't be changed so a rewrapped credential gets a new version, and
+// with it a new id, holding the same value. Deployments referencing the old id see the credential as changed and update
+// every instance using it on their next deploy, so rewrapping is limited to the prefix the operator chose. Older
+// versions keep the key version they were written with, the result reports the oldest key version still in use.
+func (ts *TransitStore) Rewrap(prefix string) (RewrapResult, error) {
+	result := RewrapResult{
+		Rewrapped:      make([]string, 0),
+		MinKeyVersions: map[string]int{},
+	}
+	if prefix == "" {
+		return result, errors.New("rewrapping needs the path of the credentials to rewrap")
+	}
+
+	names, err := ts.Store.List(prefix)
+	if err != nil {
+		return result, err
+	}
+
+	for _, name := range names {
+		latest, err := ts.Store.GetLatestByName(name)
+		if err != nil {
+			// deleted credentials are still listed
+			logger.Log.Debugf("not rewrapping %s: %s", name, err)
+		} else if key, ciphertext, ok := transitEnvelope(latest.Value); ok {
+			rewrapped, err := ts.rewrapLatest(name, key, ciphertext)
+			if err != nil {
+				return result, err
+			}
+			if rewrapped {
+				result.Rewrapped = append(result.Rewrapped, name)
+			}
+		}
+
+		err = ts.recordKeyVersions(name, result.MinKeyVersions)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (ts *TransitStore) rewrapLatest(name string, key string, ciphertext string) (bool, error) {
+	rewrappedCiphertext, err := ts.Vault.TransitRewrap(ts.Config.Mount, key, ciphertext)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("could not rewrap %s with transit key %s: %s", name, key, err))
+	}
+	keyVersion, _ := vault.TransitKeyVersion(ciphertext)
+	rewrappedKeyVersion, _ := vault.TransitKeyVersion(rewrappedCiphertext)
+	if keyVersion == rewrappedKeyVersion {
+		return false, nil
+	}
+
+	_, err = ts.Store.Set(name, map[string]interface{}{
+		TransitKeyField:        key,
+		TransitCiphertextField: rewrappedCiphertext,
+	})
+	if err != nil {
+		return false, err
+	}
+	logger.Log.Infof("rewrapped %s from version %d to version %d of transit key %s", name, keyVersion, rewrappedKeyVersion, key)
+	return true, nil
+}
+
+// Soft deleted versions can't be read and aren't counted, undeleting one may need an older key version
+func (ts *TransitStore) recordKeyVersions(name string, minKeyVersions map[string]int) error {
+	versions, err := ts.Store.GetByName(name)
+	if err != nil {
+		logger.Log.Debugf("no versions of %s to check: %s", name, err)
+		return nil
+	}
+	for _, version := range versions {
+		key, ciphertext, ok := transitEnvelope(version.Value)
+		if !ok {
+			continue
+		}
+		keyVersion, err := vault.TransitKeyVersion(ciphertext)
+		if err != nil {
+			return errors.New(fmt.Sprintf("could not read the key version of %s: %s", version.Id, err))
+		}
+		if minKeyVersion, seen := minKeyVersions[key]; !seen || keyVersion < minKeyVersion {
+			minKeyVersions[key] = keyVersion
+		}
+	}
+	return nil
+}
+
+func (ts *TransitStore) decrypt(s secret.Secret) (secret.Secret, error) {
+	key, ciphertext, ok := transitEnvelope(s.Value)
+	if !ok {
+		return s, nil
+	}
+
+	plaintext, err := ts.Vault.TransitDecrypt(ts.Config.Mount, key, ciphertext)
+	if err != nil {
+		return s, errors.New(fmt.Sprintf("could not decrypt %s with transit key %s: %s", s.Name, key, err))
+	}
+
+	// numbers are kept as json.Number like they are when read from Vault
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(plaintext))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	if err != nil {
+		return s, err
+	}
+	s.Value = value
+	return s, nil
+}
+
+func transitEnvelope(value interface{}) (string, string, bool) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+	ciphertext, ok := fields[TransitCiphertextField].(string)
+	if !ok {
+		return "", "", false
+	}
+	key, _ := fields[TransitKeyField].(string)
+	return key, ciphertext, true
+}
+
+// The credential types a value could have been stored for. Values are stored without their type so they're told apart
+// by their fields, which password, value, and json credentials share.
+func credentialTypes(value interface{}) []string {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+
+	has := func(field string) bool {
+		_, ok := fields[field]
+		return ok
+	}
+	switch {
+	case has("certificate"):
+		return []string{"certificate"}
+	case has("public_key_fingerprint"):
+		return []string{"ssh"}
+	case has("public_key") && has("private_key"):
+		return []string{"rsa"}
+	case has("username") && has("password"):
+		return []string{"user"}
+	case has("value") && len(fields) == 1:
+		if _, ok := fields["value"].(json.Number); ok {
+			return []string{"value"}
+		}
+		switch reflect.ValueOf(fields["value"]).Kind() {
+		case reflect.String:
+			return []string{"password", "value"}
+		case reflect.Map:
+			return []string{"json"}
+		default:
+			return []string{"value"}
+		}
+	default:
+		return []string{}
+	}
+}
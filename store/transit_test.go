@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transit Store", func() {
+	stored := func(name string) map[string]interface{} {
+		latest, err := healthyTransitStore.Store.GetLatestByName(name)
+		Expect(err).ToNot(HaveOccurred())
+		return latest.Value.(map[string]interface{})
+	}
+
+	It("encrypts credentials matching a rule", func() {
+		id, err := healthyTransitStore.Set("/transit/encrypted/password", map[string]interface{}{"value": "hunter2"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(stored("/transit/encrypted/password")).To(HaveKeyWithValue(store.TransitKeyField, "bosh-vault"))
+		Expect(stored("/transit/encrypted/password")).To(HaveKeyWithValue(store.TransitCiphertextField, HavePrefix("vault:v1:")))
+		Expect(stored("/transit/encrypted/password")).ToNot(HaveKey("value"))
+
+		latest, err := healthyTransitStore.GetLatestByName("/transit/encrypted/password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "hunter2"}))
+
+		byId, err := healthyTransitStore.GetById(id)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(byId.Value).To(Equal(map[string]interface{}{"value": "hunter2"}))
+
+		byName, err := healthyTransitStore.GetByName("/transit/encrypted/password")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(byName).To(HaveLen(1))
+		Expect(byName[0].Value).To(Equal(map[string]interface{}{"value": "hunter2"}))
+	})
+
+	It("stores credentials that don't match a rule as they are", func() {
+		rsaKeypair := map[string]interface{}{"public_key": "public", "private_key": "private"}
+		_, err := healthyTransitStore.Set("/transit/encrypted/rsa", rsaKeypair)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored("/transit/encrypted/rsa")).To(Equal(rsaKeypair))
+
+		_, err = healthyTransitStore.Set("/transit/elsewhere/password", map[string]interface{}{"value": "hunter2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored("/transit/elsewhere/password")).To(HaveKeyWithValue("value", "hunter2"))
+
+		_, err = healthyTransitStore.Set("/transit/everything/rsa", rsaKeypair)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored("/transit/everything/rsa")).To(HaveKey(store.TransitCiphertextField))
+	})
+
+	It("matches credential types by their fields", func() {
+		transitStore := store.TransitStore{Config: config.TransitConfiguration{
+			Rules: []config.TransitRule{{Types: []string{"json"}}},
+		}}
+		Expect(transitStore.Encrypts("/object", map[string]interface{}{"value": map[string]interface{}{"a": "b"}})).To(BeTrue())
+		Expect(transitStore.Encrypts("/string", map[string]interface{}{"value": "b"})).To(BeFalse())
+		Expect(transitStore.Encrypts("/certificate", map[string]interface{}{"certificate": "", "ca": "", "private_key": ""})).To(BeFalse())
+
+		transitStore.Config.Rules = nil
+		Expect(transitStore.Encrypts("/anything", map[string]interface{}{"value": "b"})).To(BeTrue())
+	})
+
+	It("rewraps credentials after the key is rotated", func() {
+		firstId, err := healthyTransitStore.Set("/transit/everything/rotated", map[string]interface{}{"value": "before rotation"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = healthyTransitStore.Set("/transit/everything/plain", map[string]interface{}{"value": "also encrypted"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = healthyTransitStore.Vault.Client.Logical().Write("transit/keys/bosh-vault/rotate", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		result, err := healthyTransitStore.Rewrap("/transit/everything")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rewrapped).To(ContainElement("/transit/everything/rotated"))
+		// the first version still needs the first key version
+		Expect(result.MinKeyVersions).To(HaveKeyWithValue("bosh-vault", 1))
+		Expect(stored("/transit/everything/rotated")).To(HaveKeyWithValue(store.TransitCiphertextField, HavePrefix("vault:v2:")))
+
+		latest, err := healthyTransitStore.GetLatestByName("/transit/everything/rotated")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Id).ToNot(Equal(firstId))
+		Expect(latest.Value).To(Equal(map[string]interface{}{"value": "before rotation"}))
+
+		first, err := healthyTransitStore.GetById(firstId)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.Value).To(Equal(map[string]interface{}{"value": "before rotation"}))
+
+		Expect(healthyTransitStore.Destroy("/transit/everything/rotated", []int{1})).To(Succeed())
+		result, err = healthyTransitStore.Rewrap("/transit/everything")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rewrapped).To(BeEmpty())
+		Expect(result.MinKeyVersions).To(HaveKeyWithValue("bosh-vault", 2))
+
+		_, err = healthyTransitStore.Rewrap("")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,386 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KV2 style versioning for stores that keep secrets themselves instead of in Vault. Values are kept as JSON so every
+// read returns a copy callers can't modify in place, and so the secrets can be persisted as they are.
+type versionedSecrets struct {
+	mutex        sync.RWMutex
+	secrets      map[string]*versionedSecret
+	deletePolicy string
+	// called with every secret marshaled to JSON after each change, a failing save undoes the change
+	save func(secretsJson []byte) error
+}
+
+type versionedSecret struct {
+	CurrentVersion int                    `json:"current_version"`
+	Versions       map[int]*secretVersion `json:"versions"`
+}
+
+type secretVersion struct {
+	Value        json.RawMessage `json:"value,omitempty"`
+	CreatedTime  string          `json:"created_time"`
+	DeletionTime string          `json:"deletion_time,omitempty"`
+	Destroyed    bool            `json:"destroyed,omitempty"`
+}
+
+func newVersionedSecrets(deletePolicy string) *versionedSecrets {
+	return &versionedSecrets{
+		secrets:      map[string]*versionedSecret{},
+		deletePolicy: deletePolicy,
+	}
+}
+
+func (s *versionedSecret) live(version int) bool {
+	v, ok := s.Versions[version]
+	return ok && v.DeletionTime == "" && !v.Destroyed
+}
+
+func (s *versionedSecret) clone() *versionedSecret {
+	clone := &versionedSecret{
+		CurrentVersion: s.CurrentVersion,
+		Versions:       make(map[int]*secretVersion, len(s.Versions)),
+	}
+	for version, v := range s.Versions {
+		versionClone := *v
+		clone.Versions[version] = &versionClone
+	}
+	return clone
+}
+
+// names are normalized the same way the vault package does so "name" and "/name" are the same secret
+func secretKey(name string) string {
+	name = strings.Replace(name, " ", "", -1)
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
+
+func versionId(name string, version int) (string, error) {
+	return EncodeId(VersionedSecretMetaData{
+		Name:    name,
+		Version: json.Number(strconv.Itoa(version)),
+	})
+}
+
+func (vs *versionedSecrets) load(secretsJson []byte) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	secrets := map[string]*versionedSecret{}
+	err := json.Unmarshal(secretsJson, &secrets)
+	if err != nil {
+		return err
+	}
+	vs.secrets = secrets
+	return nil
+}
+
+func (vs *versionedSecrets) count() int {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+	return len(vs.secrets)
+}
+
+// Applies the change to a copy of the secret, which is nil if it doesn't exist, and keeps the copy if the change and
+// saving succeed. A change that returns nil removes the secret.
+func (vs *versionedSecrets) update(name string, change func(s *versionedSecret) (*versionedSecret, error)) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	key := secretKey(name)
+	original, exists := vs.secrets[key]
+	var updated *versionedSecret
+	if exists {
+		updated = original.clone()
+	}
+
+	updated, err := change(updated)
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		delete(vs.secrets, key)
+	} else {
+		vs.secrets[key] = updated
+	}
+
+	if vs.save == nil {
+		return nil
+	}
+	secretsJson, err := json.Marshal(vs.secrets)
+	if err == nil {
+		err = vs.save(secretsJson)
+	}
+	if err != nil {
+		if exists {
+			vs.secrets[key] = original
+		} else {
+			delete(vs.secrets, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Saves the secrets as they are, e.g. to retry after saving failed
+func (vs *versionedSecrets) resave() error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	if vs.save == nil {
+		return nil
+	}
+	secretsJson, err := json.Marshal(vs.secrets)
+	if err != nil {
+		return err
+	}
+	return vs.save(secretsJson)
+}
+
+// Like a KV2 read the secret only exists while its current version is live
+func (vs *versionedSecrets) exists(name string) bool {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+	s, ok := vs.secrets[secretKey(name)]
+	return ok && s.live(s.CurrentVersion)
+}
+
+// Every live version of the secret newest first, deleted and destroyed versions are skipped
+func (vs *versionedSecrets) getByName(name string) ([]secret.Secret, error) {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	secrets := make([]secret.Secret, 0)
+	s, ok := vs.secrets[secretKey(name)]
+	if !ok {
+		return secrets, errors.New("secret not found")
+	}
+
+	for version := s.CurrentVersion; version > 0; version-- {
+		if !s.live(version) {
+			continue
+		}
+		versionSecret, err := versionValue(name, version, s.Versions[version])
+		if err != nil {
+			return secrets, err
+		}
+		secrets = append(secrets, versionSecret)
+	}
+	return secrets, nil
+}
+
+func (vs *versionedSecrets) getById(id string) (secret.Secret, error) {
+	decodedId, err := DecodeId(id)
+	if err != nil {
+		return secret.Secret{}, errors.New("malformed or invalid id")
+	}
+	version, err := decodedId.Version.Int64()
+	if err != nil {
+		return secret.Secret{}, errors.New("malformed or invalid id")
+	}
+
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	s, ok := vs.secrets[secretKey(decodedId.Name)]
+	if !ok {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+	// like KV2, version 0 is the latest version
+	if version == 0 {
+		version = int64(s.CurrentVersion)
+	}
+	if !s.live(int(version)) {
+		return secret.Secret{}, errors.New("secret not found")
+	}
+
+	versionSecret, err := versionValue(decodedId.Name, int(version), s.Versions[int(version)])
+	if err != nil {
+		return versionSecret, err
+	}
+	versionSecret.Id = id
+	return versionSecret, nil
+}
+
+func (vs *versionedSecrets) set(name string, value interface{}) (string, error) {
+	valueJson, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	var version int
+	err = vs.update(name, func(s *versionedSecret) (*versionedSecret, error) {
+		if s == nil {
+			s = &versionedSecret{Versions: map[int]*secretVersion{}}
+		}
+		s.CurrentVersion++
+		s.Versions[s.CurrentVersion] = &secretVersion{
+			Value:       valueJson,
+			CreatedTime: versionTimestamp(),
+		}
+		version = s.CurrentVersion
+		return s, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return versionId(name, version)
+}
+
+// Deletes according to the delete policy, it's an error to delete a secret without any live versions
+func (vs *versionedSecrets) deleteByName(name string) error {
+	return vs.update(name, func(s *versionedSecret) (*versionedSecret, error) {
+		if s == nil {
+			return nil, errors.New("secret not found")
+		}
+
+		liveVersions := make([]int, 0)
+		for version := s.CurrentVersion; version > 0; version-- {
+			if s.live(version) {
+				liveVersions = append(liveVersions, version)
+			}
+		}
+		if len(liveVersions) == 0 {
+			return nil, errors.New("secret not found")
+		}
+
+		switch vs.deletePolicy {
+		case config.DeletePolicyLatestSoft:
+			if liveVersions[0] != s.CurrentVersion {
+				return nil, errors.New("secret not found")
+			}
+			liveVersions = liveVersions[:1]
+		case config.DeletePolicyMetadataDestroy:
+			return nil, nil
+		}
+
+		deletionTime := versionTimestamp()
+		for _, version := range liveVersions {
+			s.Versions[version].DeletionTime = deletionTime
+		}
+		return s, nil
+	})
+}
+
+// Names of every secret under the prefix, including secrets whose versions are all deleted
+func (vs *versionedSecrets) list(prefix string) []string {
+	prefix = secretKey(prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	names := make([]string, 0)
+	for name := range vs.secrets {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Every version of the secret newest first, including soft deleted and destroyed versions
+func (vs *versionedSecrets) getVersions(name string) ([]secret.Version, error) {
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+
+	versions := make([]secret.Version, 0)
+	s, ok := vs.secrets[secretKey(name)]
+	if !ok {
+		return versions, errors.New("secret not found")
+	}
+
+	for version, v := range s.Versions {
+		id, err := versionId(name, version)
+		if err != nil {
+			return versions, err
+		}
+		versions = append(versions, secret.Version{
+			Id:           id,
+			Version:      version,
+			CreatedTime:  v.CreatedTime,
+			DeletionTime: v.DeletionTime,
+			Destroyed:    v.Destroyed,
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+	return versions, nil
+}
+
+// Restores soft deleted versions, destroyed versions can't be recovered
+func (vs *versionedSecrets) undelete(name string, versions []int) error {
+	return vs.update(name, func(s *versionedSecret) (*versionedSecret, error) {
+		if s == nil {
+			return nil, errors.New("secret not found")
+		}
+		for _, version := range versions {
+			if v, ok := s.Versions[version]; ok && !v.Destroyed {
+				v.DeletionTime = ""
+			}
+		}
+		return s, nil
+	})
+}
+
+// Permanently removes the values of the versions, their metadata is kept and they are reported as destroyed
+func (vs *versionedSecrets) destroy(name string, versions []int) error {
+	return vs.update(name, func(s *versionedSecret) (*versionedSecret, error) {
+		if s == nil {
+			return nil, errors.New("secret not found")
+		}
+		for _, version := range versions {
+			if v, ok := s.Versions[version]; ok {
+				v.Value = nil
+				v.Destroyed = true
+			}
+		}
+		return s, nil
+	})
+}
+
+func versionValue(name string, version int, v *secretVersion) (secret.Secret, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(v.Value))
+	// numbers are decoded the same way the Vault api decodes them
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return secret.Secret{}, errors.New(fmt.Sprintf("value of %s version %d is malformed: %s", name, version, err))
+	}
+
+	id, err := versionId(name, version)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+
+	return secret.Secret{
+		Id:    id,
+		Name:  name,
+		Value: value,
+	}, nil
+}
+
+func versionTimestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
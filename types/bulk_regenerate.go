@@ -0,0 +1,270 @@
+package types
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"strings"
+	"time"
+)
+
+type BulkRegenerateRequest struct {
+	SignedBy string `json:"signed_by"`
+}
+
+type RegeneratedCredential struct {
+	Name       string `json:"name"`
+	PreviousId string `json:"previous_id"`
+	Id         string `json:"id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Incomplete is set when the context was done before every certificate was regenerated
+type BulkRegenerateResponse struct {
+	SignedBy               string                  `json:"signed_by"`
+	RegeneratedCredentials []RegeneratedCredential `json:"regenerated_credentials"`
+	Incomplete             bool                    `json:"incomplete,omitempty"`
+}
+
+// Ordered so that reconstructed requests are deterministic
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digital_signature"},
+	{x509.KeyUsageKeyEncipherment, "key_encipherment"},
+	{x509.KeyUsageContentCommitment, "non_repudiation"},
+	{x509.KeyUsageDataEncipherment, "data_encipherment"},
+	{x509.KeyUsageKeyAgreement, "key_agreement"},
+	{x509.KeyUsageCertSign, "key_cert_sign"},
+	{x509.KeyUsageCRLSign, "crl_sign"},
+	{x509.KeyUsageEncipherOnly, "encipher_only"},
+	{x509.KeyUsageDecipherOnly, "decipher_only"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageClientAuth:      "client_auth",
+	x509.ExtKeyUsageServerAuth:      "server_auth",
+	x509.ExtKeyUsageCodeSigning:     "code_signing",
+	x509.ExtKeyUsageEmailProtection: "email_protection",
+	x509.ExtKeyUsageTimeStamping:    "timestamping",
+}
+
+// Finds every certificate signed by the named CA and reissues it with the CA's current version. Certificates are
+// matched by the ca parameter they were generated with or, for certificates without stored generation parameters, by
+// an authority key id matching any version of the CA. Failures are reported per credential rather than aborting, once
+// the context is done no further certificates are regenerated and the ones regenerated so far are reported.
+func BulkRegenerate(ctx context.Context, secretStore secret.Store, caName string) (BulkRegenerateResponse, error) {
+	response := BulkRegenerateResponse{
+		SignedBy:               caName,
+		RegeneratedCredentials: make([]RegeneratedCredential, 0),
+	}
+
+	caSubjectKeyIds, err := certificateSubjectKeyIds(secretStore, caName)
+	if err != nil {
+		return response, errors.New(fmt.Sprintf("problem reading CA %s: %s", caName, err))
+	}
+
+	rootCaCert, rootCaKey, transitionalCaPem, err := getRootCaAndKeyByName(caName, secretStore)
+	if err != nil {
+		return response, errors.New(fmt.Sprintf("problem reading CA %s: %s", caName, err))
+	}
+
+	names, err := secretStore.List("/")
+	if err != nil {
+		return response, err
+	}
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			response.Incomplete = true
+			break
+		}
+		if IsReservedName(name) || sameCredentialName(name, caName) {
+			continue
+		}
+
+		latest, err := secretStore.GetLatestByName(name)
+		if err != nil {
+			logger.Log.Debugf("skipping %s during bulk regeneration: %s", name, err)
+			continue
+		}
+		cert, err := parseCertificateValue(latest.Value)
+		if err != nil {
+			continue
+		}
+
+		certificateRequest, signed := signedCertificateRequest(secretStore, name, caName, cert, caSubjectKeyIds)
+		if !signed {
+			continue
+		}
+
+		result := RegeneratedCredential{
+			Name:       name,
+			PreviousId: latest.Id,
+		}
+
+		var credential CredentialRecordInterface
+		if certificateRequest.IsIntermediateCaRequest() {
+			credential, err = certificateRequest.GenerateIntermediateCertificate(rootCaCert, rootCaKey)
+		} else {
+			credential, err = certificateRequest.GenerateRegularCertificate(rootCaCert, rootCaKey)
+		}
+		if err == nil {
+			record := credential.(CertificateRecord)
+			record.Ca += transitionalCaPem
+			_, err = record.Store(secretStore, name)
+		}
+		if err != nil {
+			logger.Log.Errorf("problem regenerating %s signed by %s: %s", name, caName, err)
+			result.Error = err.Error()
+			response.RegeneratedCredentials = append(response.RegeneratedCredentials, result)
+			continue
+		}
+
+		err = StoreGenerationRequest(secretStore, certificateRequest)
+		if err != nil {
+			logger.Log.Errorf("problem storing generation parameters for %s: %s", name, err)
+		}
+
+		regenerated, err := secretStore.GetLatestByName(name)
+		if err == nil {
+			result.Id = regenerated.Id
+		}
+		response.RegeneratedCredentials = append(response.RegeneratedCredentials, result)
+	}
+
+	return response, nil
+}
+
+// Returns the request that should be used to reissue the certificate if it was signed by the named CA
+func signedCertificateRequest(secretStore secret.Store, name string, caName string, cert *x509.Certificate, caSubjectKeyIds [][]byte) (*CertificateRequest, bool) {
+	storedRequest, err := GetGenerationRequest(secretStore, name)
+	if err == nil {
+		certificateRequest, ok := storedRequest.(*CertificateRequest)
+		if !ok || certificateRequest.Parameters.SelfSign {
+			return nil, false
+		}
+		return certificateRequest, sameCredentialName(certificateRequest.Parameters.Ca, caName)
+	}
+
+	// self signed certificates reference their own key
+	if len(cert.AuthorityKeyId) == 0 || string(cert.AuthorityKeyId) == string(cert.SubjectKeyId) {
+		return nil, false
+	}
+	for _, caSubjectKeyId := range caSubjectKeyIds {
+		if string(cert.AuthorityKeyId) == string(caSubjectKeyId) {
+			return certificateRequestFromCertificate(name, caName, cert), true
+		}
+	}
+	return nil, false
+}
+
+// Every version of the CA is considered since certificates that need to be reissued were signed by an older version
+func certificateSubjectKeyIds(secretStore secret.Store, name string) ([][]byte, error) {
+	secretStore, err := caStore(secretStore, name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := secretStore.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyIds := make([][]byte, 0)
+	for _, version := range versions {
+		cert, err := parseCertificateValue(version.Value)
+		if err != nil {
+			continue
+		}
+		subjectKeyIds = append(subjectKeyIds, cert.SubjectKeyId)
+	}
+	return subjectKeyIds, nil
+}
+
+func parseCertificateValue(value interface{}) (*x509.Certificate, error) {
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a certificate")
+	}
+	certificatePem, ok := valueMap["certificate"].(string)
+	if !ok {
+		return nil, errors.New("not a certificate")
+	}
+	block, _ := pem.Decode([]byte(certificatePem))
+	if block == nil {
+		return nil, errors.New("unable to decode certificate pem")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// Certificates that were set rather than generated have no stored parameters, reissue them with the same subject,
+// alternative names, usages, key type, and lifetime as the existing certificate
+func certificateRequestFromCertificate(name string, caName string, cert *x509.Certificate) *CertificateRequest {
+	request := &CertificateRequest{
+		Name: name,
+		Type: CertificateType,
+		Parameters: CertificateParams{
+			CommonName:         cert.Subject.CommonName,
+			IsCa:               cert.IsCA,
+			Ca:                 caName,
+			Organization:       firstOrEmpty(cert.Subject.Organization),
+			OrganizationalUnit: firstOrEmpty(cert.Subject.OrganizationalUnit),
+			Locality:           firstOrEmpty(cert.Subject.Locality),
+			State:              firstOrEmpty(cert.Subject.Province),
+			Country:            firstOrEmpty(cert.Subject.Country),
+			Duration:           int(cert.NotAfter.Sub(cert.NotBefore) / (24 * time.Hour)),
+		},
+	}
+
+	request.Parameters.AlternativeNames = append(request.Parameters.AlternativeNames, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		request.Parameters.AlternativeNames = append(request.Parameters.AlternativeNames, ip.String())
+	}
+
+	for _, keyUsage := range keyUsageNames {
+		if cert.KeyUsage&keyUsage.usage != 0 {
+			request.Parameters.KeyUsage = append(request.Parameters.KeyUsage, keyUsage.name)
+		}
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usageName, ok := extKeyUsageNames[usage]; ok {
+			request.Parameters.ExtendedKeyUsage = append(request.Parameters.ExtendedKeyUsage, usageName)
+		}
+	}
+
+	switch publicKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		request.Parameters.KeyType = KeyTypeRsa
+		request.Parameters.KeyLength = publicKey.N.BitLen()
+	case *ecdsa.PublicKey:
+		request.Parameters.KeyType = KeyTypeEcdsaP256
+		if publicKey.Curve == elliptic.P384() {
+			request.Parameters.KeyType = KeyTypeEcdsaP384
+		}
+	case ed25519.PublicKey:
+		request.Parameters.KeyType = KeyTypeEd25519
+	}
+
+	return request
+}
+
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func sameCredentialName(a, b string) bool {
+	return strings.TrimPrefix(a, "/") == strings.TrimPrefix(b, "/")
+}
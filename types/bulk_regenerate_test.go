@@ -0,0 +1,114 @@
+package types_test
+
+import (
+	"context"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bulk Regeneration", func() {
+	withEachStore(bulkRegenerateSpecs)
+})
+
+func bulkRegenerateSpecs() {
+	generateAndStore := func(request types.CredentialGenerationRequest, storeParameters bool) {
+		credential, err := request.Generate(secretStore)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = credential.Store(secretStore, request.CredentialName())
+		Expect(err).ToNot(HaveOccurred())
+		if storeParameters {
+			Expect(types.StoreGenerationRequest(secretStore, request)).To(Succeed())
+		}
+	}
+
+	It("reissues every certificate signed by the CA and nothing else", func() {
+		caRequest := &types.CertificateRequest{
+			Name: "/bulk/ca",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				IsCa:       true,
+				CommonName: "bulk ca",
+			},
+		}
+		generateAndStore(caRequest, true)
+		generateAndStore(&types.CertificateRequest{
+			Name: "/bulk/other_ca",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				IsCa:       true,
+				CommonName: "other ca",
+			},
+		}, true)
+		// one leaf has generation parameters and one only has an authority key id linking it to the CA
+		generateAndStore(&types.CertificateRequest{
+			Name: "/bulk/deployment/with_parameters",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/bulk/ca",
+				CommonName: "with parameters",
+			},
+		}, true)
+		generateAndStore(&types.CertificateRequest{
+			Name: "/bulk/deployment/without_parameters",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/bulk/ca",
+				CommonName: "without parameters",
+			},
+		}, false)
+		generateAndStore(&types.CertificateRequest{
+			Name: "/bulk/deployment/other_leaf",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/bulk/other_ca",
+				CommonName: "other leaf",
+			},
+		}, true)
+
+		// rotate the CA
+		generateAndStore(caRequest, true)
+
+		response, err := types.BulkRegenerate(context.Background(), secretStore, "/bulk/ca")
+		Expect(err).ToNot(HaveOccurred())
+		regeneratedNames := make([]string, 0)
+		for _, regenerated := range response.RegeneratedCredentials {
+			Expect(regenerated.Error).To(BeEmpty())
+			Expect(regenerated.Id).ToNot(Equal(regenerated.PreviousId))
+			regeneratedNames = append(regeneratedNames, regenerated.Name)
+		}
+		Expect(regeneratedNames).To(ConsistOf("/bulk/deployment/with_parameters", "/bulk/deployment/without_parameters"))
+
+		ca, err := secretStore.GetLatestByName("/bulk/ca")
+		Expect(err).ToNot(HaveOccurred())
+		leaf, err := secretStore.GetLatestByName("/bulk/deployment/without_parameters")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(leaf.Value.(map[string]interface{})["ca"]).To(Equal(ca.Value.(map[string]interface{})["certificate"]))
+	})
+
+	It("stops regenerating once the context is done", func() {
+		generateAndStore(&types.CertificateRequest{
+			Name: "/stopped/ca",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				IsCa:       true,
+				CommonName: "stopped ca",
+			},
+		}, true)
+		generateAndStore(&types.CertificateRequest{
+			Name: "/stopped/leaf",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/stopped/ca",
+				CommonName: "stopped leaf",
+			},
+		}, true)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		response, err := types.BulkRegenerate(ctx, secretStore, "/stopped/ca")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Incomplete).To(BeTrue())
+		Expect(response.RegeneratedCredentials).To(BeEmpty())
+	})
+}
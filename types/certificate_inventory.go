@@ -0,0 +1,94 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"time"
+)
+
+// Filters for CertificateInventory, zero values disable a filter
+type CertificateInventoryRequest struct {
+	ExpiresWithinDays int    `json:"expires_within_days,omitempty"`
+	SignedBy          string `json:"signed_by,omitempty"`
+}
+
+type CertificateInventoryEntry struct {
+	Name     string    `json:"name"`
+	Id       string    `json:"id"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+	IsCa     bool      `json:"is_ca"`
+}
+
+type CertificateInventoryResponse struct {
+	Certificates []CertificateInventoryEntry `json:"certificates"`
+}
+
+// Reports the latest version of every certificate in the store. Certificates match the signed_by filter the same way
+// they are matched for bulk regeneration, by the ca parameter they were generated with or by authority key id.
+func CertificateInventory(secretStore secret.Store, request CertificateInventoryRequest) (CertificateInventoryResponse, error) {
+	response := CertificateInventoryResponse{
+		Certificates: make([]CertificateInventoryEntry, 0),
+	}
+
+	if request.ExpiresWithinDays < 0 {
+		return response, errors.New("expires_within_days must not be negative")
+	}
+
+	var caSubjectKeyIds [][]byte
+	if request.SignedBy != "" {
+		var err error
+		caSubjectKeyIds, err = certificateSubjectKeyIds(secretStore, request.SignedBy)
+		if err != nil {
+			return response, errors.New(fmt.Sprintf("problem reading CA %s: %s", request.SignedBy, err))
+		}
+	}
+
+	names, err := secretStore.List("/")
+	if err != nil {
+		return response, err
+	}
+
+	expiresBefore := time.Now().AddDate(0, 0, request.ExpiresWithinDays)
+	for _, name := range names {
+		if IsReservedName(name) {
+			continue
+		}
+
+		latest, err := secretStore.GetLatestByName(name)
+		if err != nil {
+			logger.Log.Debugf("skipping %s in certificate inventory: %s", name, err)
+			continue
+		}
+		cert, err := parseCertificateValue(latest.Value)
+		if err != nil {
+			continue
+		}
+
+		if request.ExpiresWithinDays > 0 && cert.NotAfter.After(expiresBefore) {
+			continue
+		}
+		if request.SignedBy != "" {
+			if sameCredentialName(name, request.SignedBy) {
+				continue
+			}
+			if _, signed := signedCertificateRequest(secretStore, name, request.SignedBy, cert, caSubjectKeyIds); !signed {
+				continue
+			}
+		}
+
+		response.Certificates = append(response.Certificates, CertificateInventoryEntry{
+			Name:     name,
+			Id:       latest.Id,
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+			IsCa:     cert.IsCA,
+		})
+	}
+
+	return response, nil
+}
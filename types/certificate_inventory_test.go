@@ -0,0 +1,80 @@
+package types_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificate Inventory", func() {
+	withEachStore(certificateInventorySpecs)
+})
+
+func certificateInventorySpecs() {
+	generateAndStore := func(request types.CredentialGenerationRequest) {
+		credential, err := request.Generate(secretStore)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = credential.Store(secretStore, request.CredentialName())
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		generateAndStore(&types.CertificateRequest{
+			Name: "/inventory/ca",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				IsCa:       true,
+				CommonName: "inventory ca",
+			},
+		})
+		generateAndStore(&types.CertificateRequest{
+			Name: "/inventory/deployment/short_lived",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/inventory/ca",
+				CommonName: "short lived",
+				Duration:   10,
+			},
+		})
+		generateAndStore(&types.CertificateRequest{
+			Name: "/inventory/deployment/long_lived",
+			Type: types.CertificateType,
+			Parameters: types.CertificateParams{
+				Ca:         "/inventory/ca",
+				CommonName: "long lived",
+				Duration:   365,
+			},
+		})
+	})
+
+	It("lists the certificates signed by a CA", func() {
+		response, err := types.CertificateInventory(secretStore, types.CertificateInventoryRequest{
+			SignedBy: "/inventory/ca",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Certificates).To(HaveLen(2))
+		for _, certificate := range response.Certificates {
+			Expect(certificate.Issuer).To(ContainSubstring("inventory ca"))
+			Expect(certificate.IsCa).To(BeFalse())
+			Expect(certificate.Id).ToNot(BeEmpty())
+		}
+	})
+
+	It("only lists certificates that expire within the given number of days", func() {
+		response, err := types.CertificateInventory(secretStore, types.CertificateInventoryRequest{
+			SignedBy:          "/inventory/ca",
+			ExpiresWithinDays: 30,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Certificates).To(HaveLen(1))
+		Expect(response.Certificates[0].Name).To(Equal("/inventory/deployment/short_lived"))
+		Expect(response.Certificates[0].Subject).To(ContainSubstring("short lived"))
+	})
+
+	It("rejects a negative number of days", func() {
+		_, err := types.CertificateInventory(secretStore, types.CertificateInventoryRequest{
+			ExpiresWithinDays: -1,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+}
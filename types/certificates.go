@@ -1,9 +1,9 @@
 package types
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -41,8 +41,10 @@ type CertificateParams struct {
 	Country            string   `json:"country"`
 	KeyUsage           []string `json:"key_usage"`
 	KeyLength          int      `json:"key_length"`
+	KeyType            string   `json:"key_type,omitempty"`
 	Duration           int      `json:"duration"`
 	SelfSign           bool     `json:"self_sign"`
+	Transitional       bool     `json:"transitional,omitempty"`
 }
 
 type CertificateResponse struct {
@@ -51,10 +53,13 @@ type CertificateResponse struct {
 	Value CertificateRecord `json:"value"`
 }
 
+// A transitional CA version is trusted but never used for signing, while one is marked the ca field of certificates
+// signed by the active version contains both CAs so deployments can trust the old and new CA during a rotation
 type CertificateRecord struct {
-	Certificate string `json:"certificate"`
-	Ca          string `json:"ca"`
-	PrivateKey  string `json:"private_key"`
+	Certificate  string `json:"certificate"`
+	Ca           string `json:"ca"`
+	PrivateKey   string `json:"private_key"`
+	Transitional bool   `json:"transitional,omitempty"`
 }
 
 func (r *CertificateRequest) CredentialType() string {
@@ -66,7 +71,8 @@ func (r *CertificateRequest) CredentialName() string {
 }
 
 func (r *CertificateRequest) Validate() bool {
-	return r.IsRootCaRequest() || r.IsIntermediateCaRequest() || r.IsRegularCertificateRequest()
+	return validKeyType(r.Parameters.KeyType) &&
+		(r.IsRootCaRequest() || r.IsIntermediateCaRequest() || r.IsRegularCertificateRequest())
 }
 
 func (r CertificateRequest) IsRootCaRequest() bool {
@@ -103,6 +109,14 @@ func (record CertificateRecord) Store(secretStore secret.Store, name string) (Cr
 		return resp, err
 	}
 
+	// the new version replaces any other transitional version of the CA
+	if record.Transitional {
+		err = SetTransitionalVersion(secretStore, name, id)
+		if err != nil {
+			return resp, err
+		}
+	}
+
 	resp = CertificateResponse{
 		Name:  name,
 		Id:    id,
@@ -114,7 +128,8 @@ func (record CertificateRecord) Store(secretStore secret.Store, name string) (Cr
 
 func (r *CertificateRequest) Generate(secretStore secret.Store) (CredentialRecordInterface, error) {
 	var rootCaCert *x509.Certificate
-	var rootCaKey *rsa.PrivateKey
+	var rootCaKey crypto.Signer
+	var transitionalCaPem string
 	var err error
 
 	if r.IsRegularCertificateRequest() || r.IsIntermediateCaRequest() {
@@ -122,40 +137,41 @@ func (r *CertificateRequest) Generate(secretStore secret.Store) (CredentialRecor
 			rootCaKey = nil
 			rootCaCert = nil
 		} else {
-			rootCaCert, rootCaKey, err = getRootCaAndKeyByName(r.Parameters.Ca, secretStore)
+			rootCaCert, rootCaKey, transitionalCaPem, err = getRootCaAndKeyByName(r.Parameters.Ca, secretStore)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
+	var credential CredentialRecordInterface
 	switch {
 	case r.IsRegularCertificateRequest():
-		return r.GenerateRegularCertificate(rootCaCert, rootCaKey)
+		credential, err = r.GenerateRegularCertificate(rootCaCert, rootCaKey)
 	case r.IsIntermediateCaRequest():
-		return r.GenerateIntermediateCertificate(rootCaCert, rootCaKey)
+		credential, err = r.GenerateIntermediateCertificate(rootCaCert, rootCaKey)
 	case r.IsRootCaRequest():
-		return r.GenerateRootCertificate()
+		credential, err = r.GenerateRootCertificate()
 	default:
 		return nil, errors.New("unable to generate cert, unknown type, make sure to call Validate on the request before trying to Generate")
 	}
-}
-
-func getRsaKey(keyLength int) (*rsa.PrivateKey, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, keyLength)
 	if err != nil {
-		return &rsa.PrivateKey{}, errors.New(fmt.Sprintf("Problem generating RSA keypair for cert: %s", err))
+		return nil, err
 	}
-	return privateKey, err
+
+	record := credential.(CertificateRecord)
+	record.Ca += transitionalCaPem
+	record.Transitional = r.Parameters.IsCa && r.Parameters.Transitional
+	return record, nil
 }
 
-func newX509CertAndKey(cr *CertificateRequest) (x509.Certificate, *rsa.PrivateKey, error) {
+func newX509CertAndKey(cr *CertificateRequest) (x509.Certificate, crypto.Signer, error) {
 
 	if cr.Parameters.KeyLength == 0 {
 		cr.Parameters.KeyLength = CertificateDefaultRsaKeyBits
 	}
 
-	privateKey, err := getRsaKey(cr.Parameters.KeyLength)
+	privateKey, err := generatePrivateKey(cr.Parameters.KeyType, cr.Parameters.KeyLength)
 	if err != nil {
 		return x509.Certificate{}, privateKey, err
 	}
@@ -173,9 +189,10 @@ func newX509CertAndKey(cr *CertificateRequest) (x509.Certificate, *rsa.PrivateKe
 
 	notAfter := now.Add(time.Duration(cr.Parameters.Duration*24) * time.Hour)
 
-	subjectKeyHash := sha1.New()
-	subjectKeyHash.Write(privateKey.N.Bytes())
-	subjectKeyId := subjectKeyHash.Sum(nil)
+	subjectKeyId, err := generateSubjectKeyId(privateKey.Public())
+	if err != nil {
+		return x509.Certificate{}, privateKey, errors.New(fmt.Sprintf("error generating cert subject key id %s", err))
+	}
 
 	if cr.Parameters.Organization == "" {
 		cr.Parameters.Organization = CertificateDefaultOrg
@@ -254,32 +271,75 @@ func newX509CertAndKey(cr *CertificateRequest) (x509.Certificate, *rsa.PrivateKe
 	return cert, privateKey, nil
 }
 
-func getRootCaAndKeyByName(caName string, store secret.Store) (*x509.Certificate, *rsa.PrivateKey, error) {
+// Stores that limit which CAs a client may sign with implement this, e.g. a policy.Store. It returns the store the CA
+// should be read from, which may allow reading a CA the client can sign with but not read itself.
+type CaAuthorizer interface {
+	AuthorizeCa(caName string) (secret.Store, error)
+}
+
+func caStore(store secret.Store, caName string) (secret.Store, error) {
+	if authorizer, ok := store.(CaAuthorizer); ok {
+		return authorizer.AuthorizeCa(caName)
+	}
+	return store, nil
+}
+
+// Signing always uses the active CA, the newest version that isn't marked transitional. The version marked transitional
+// is never signed with but its certificate is returned as well so it can be trusted alongside the active CA, whether it
+// is older or newer than the active one.
+// CAs may use any supported key algorithm, the key is parsed generically so whatever type it is can be used for signing
+func getRootCaAndKeyByName(caName string, store secret.Store) (*x509.Certificate, crypto.Signer, string, error) {
 	rootCaCert := &x509.Certificate{}
-	rootCaKey := &rsa.PrivateKey{}
+	store, err := caStore(store, caName)
+	if err != nil {
+		return rootCaCert, nil, "", err
+	}
+
 	rawCaResponse, err := store.GetByName(caName)
 	if err != nil {
-		return rootCaCert, rootCaKey, err
+		return rootCaCert, nil, "", err
+	}
+
+	transitionalId := GetTransitionalVersion(store, caName)
+	var caRecord map[string]interface{}
+	transitionalCaPem := ""
+	for _, caVersion := range rawCaResponse {
+		// anything that isn't a certificate record can't be signed with
+		versionRecord, ok := caVersion.Value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if caVersion.Id == transitionalId {
+			transitionalCaPem, _ = versionRecord["certificate"].(string)
+		} else if caRecord == nil {
+			caRecord = versionRecord
+		}
+	}
+	if caRecord == nil {
+		return rootCaCert, nil, "", errors.New(fmt.Sprintf("%s has no active certificate to sign with", caName))
 	}
 
-	caRecord := rawCaResponse[0].Value.(map[string]interface{})
+	caCertPem, _ := caRecord["certificate"].(string)
+	caKeyPem, _ := caRecord["private_key"].(string)
 
-	cpb, _ := pem.Decode([]byte(caRecord["certificate"].(string)))
+	cpb, _ := pem.Decode([]byte(caCertPem))
+	if cpb == nil {
+		return rootCaCert, nil, "", errors.New(fmt.Sprintf("unable to decode the certificate of %s", caName))
+	}
 	rootCaCert, err = x509.ParseCertificate(cpb.Bytes)
 	if err != nil {
-		return rootCaCert, rootCaKey, err
+		return rootCaCert, nil, "", err
 	}
 
-	caPrivKey, _ := pem.Decode([]byte(caRecord["private_key"].(string)))
-	rootCaKey, err = x509.ParsePKCS1PrivateKey(caPrivKey.Bytes)
+	rootCaKey, err := parsePrivateKeyPem(caKeyPem)
 	if err != nil {
-		return rootCaCert, rootCaKey, err
+		return rootCaCert, nil, "", err
 	}
 
-	return rootCaCert, rootCaKey, nil
+	return rootCaCert, rootCaKey, transitionalCaPem, nil
 }
 
-func assembleCertRecord(rawCaCert, rawCert, rawKey []byte) CertificateRecord {
+func assembleCertRecord(rawCaCert, rawCert []byte, privateKey crypto.Signer) (CertificateRecord, error) {
 	pemCa := pem.EncodeToMemory(&pem.Block{
 		Type:  "CERTIFICATE",
 		Bytes: rawCaCert,
@@ -290,28 +350,31 @@ func assembleCertRecord(rawCaCert, rawCert, rawKey []byte) CertificateRecord {
 		Bytes: rawCert,
 	})
 
-	pemPrivateKey := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: rawKey,
-	})
+	pemPrivateKey, err := marshalPrivateKeyPem(privateKey, rsaPemType)
+	if err != nil {
+		return CertificateRecord{}, err
+	}
 
 	return CertificateRecord{
 		Certificate: string(pemCert),
 		Ca:          string(pemCa),
 		PrivateKey:  string(pemPrivateKey),
-	}
+	}, nil
 }
 
-func (r *CertificateRequest) GenerateRegularCertificate(rootCaCert *x509.Certificate, rootCaKey *rsa.PrivateKey) (CredentialRecordInterface, error) {
+func (r *CertificateRequest) GenerateRegularCertificate(rootCaCert *x509.Certificate, rootCaKey crypto.Signer) (CredentialRecordInterface, error) {
 
 	certTemplate, privateKey, err := newX509CertAndKey(r)
 	if err != nil {
 		return nil, err
 	}
 
-	// Default key usage for standard TLS certificate
+	// Default key usage for standard TLS certificate, key encipherment only makes sense for RSA keys
 	if len(r.Parameters.KeyUsage) == 0 {
-		certTemplate.KeyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+		certTemplate.KeyUsage = x509.KeyUsageDigitalSignature
+		if _, isRsa := privateKey.(*rsa.PrivateKey); isRsa {
+			certTemplate.KeyUsage |= x509.KeyUsageKeyEncipherment
+		}
 	}
 
 	// Default key usage for "regular" MTLS cert is "server_auth"
@@ -335,12 +398,12 @@ func (r *CertificateRequest) GenerateRegularCertificate(rootCaCert *x509.Certifi
 		}
 	}
 
-	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, rootCaCert, &privateKey.PublicKey, rootCaKey)
+	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, rootCaCert, privateKey.Public(), rootCaKey)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("problem generating the x509 CA cert: %s", err))
 	}
 
-	return assembleCertRecord(rootCaCert.Raw, rawCert, x509.MarshalPKCS1PrivateKey(privateKey)), nil
+	return assembleCertRecord(rootCaCert.Raw, rawCert, privateKey)
 }
 
 func (r *CertificateRequest) GenerateRootCertificate() (CredentialRecordInterface, error) {
@@ -357,15 +420,15 @@ func (r *CertificateRequest) GenerateRootCertificate() (CredentialRecordInterfac
 
 	certTemplate.AuthorityKeyId = certTemplate.SubjectKeyId
 
-	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, &certTemplate, &privateKey.PublicKey, privateKey)
+	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, &certTemplate, privateKey.Public(), privateKey)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("problem generating the x509 CA cert: %s", err))
 	}
 
-	return assembleCertRecord(rawCert, rawCert, x509.MarshalPKCS1PrivateKey(privateKey)), nil
+	return assembleCertRecord(rawCert, rawCert, privateKey)
 }
 
-func (r *CertificateRequest) GenerateIntermediateCertificate(rootCaCert *x509.Certificate, rootCaKey *rsa.PrivateKey) (CredentialRecordInterface, error) {
+func (r *CertificateRequest) GenerateIntermediateCertificate(rootCaCert *x509.Certificate, rootCaKey crypto.Signer) (CredentialRecordInterface, error) {
 
 	certTemplate, privateKey, err := newX509CertAndKey(r)
 	if err != nil {
@@ -384,10 +447,10 @@ func (r *CertificateRequest) GenerateIntermediateCertificate(rootCaCert *x509.Ce
 
 	certTemplate.AuthorityKeyId = rootCaCert.SubjectKeyId
 
-	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, rootCaCert, &privateKey.PublicKey, rootCaKey)
+	rawCert, err := x509.CreateCertificate(rand.Reader, &certTemplate, rootCaCert, privateKey.Public(), rootCaKey)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("problem generating the x509 CA cert: %s", err))
 	}
 
-	return assembleCertRecord(rootCaCert.Raw, rawCert, x509.MarshalPKCS1PrivateKey(privateKey)), nil
+	return assembleCertRecord(rootCaCert.Raw, rawCert, privateKey)
 }
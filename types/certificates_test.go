@@ -14,6 +14,10 @@ import (
 )
 
 var _ = Describe("Certificates", func() {
+	withEachStore(certificateSpecs)
+})
+
+func certificateSpecs() {
 
 	Describe("Certificate Request Methods", func() {
 		Context("A valid CA request", func() {
@@ -80,9 +84,9 @@ var _ = Describe("Certificates", func() {
 						CommonName: "goinggoingbackbacktocaca",
 					},
 				}
-				caCert, err := caReq.Generate(&healthySimpleStore)
+				caCert, err := caReq.Generate(secretStore)
 				Expect(err).ToNot(HaveOccurred())
-				_, err = caCert.Store(&healthySimpleStore, caReq.Name)
+				_, err = caCert.Store(secretStore, caReq.Name)
 				Expect(err).ToNot(HaveOccurred())
 				intermediate := types.CertificateRequest{
 					Name: "intermediate",
@@ -93,7 +97,7 @@ var _ = Describe("Certificates", func() {
 						CommonName: "Tahoe",
 					},
 				}
-				generatedIntCa, err := intermediate.Generate(&healthySimpleStore)
+				generatedIntCa, err := intermediate.Generate(secretStore)
 				Expect(err).ToNot(HaveOccurred())
 				intCaRecord := generatedIntCa.(types.CertificateRecord)
 				Expect(intCaRecord.Ca).To(Equal(caCert.(types.CertificateRecord).Certificate))
@@ -133,9 +137,9 @@ var _ = Describe("Certificates", func() {
 						CommonName: "goinggoingbackbacktocaca",
 					},
 				}
-				caCert, err := caReq.Generate(&healthySimpleStore)
+				caCert, err := caReq.Generate(secretStore)
 				Expect(err).ToNot(HaveOccurred())
-				_, err = caCert.Store(&healthySimpleStore, caReq.Name)
+				_, err = caCert.Store(secretStore, caReq.Name)
 				Expect(err).ToNot(HaveOccurred())
 				leafCertRequest := types.CertificateRequest{
 					Name: "leaf",
@@ -145,7 +149,7 @@ var _ = Describe("Certificates", func() {
 						CommonName: "Leafy",
 					},
 				}
-				generatedLeafCert, err := leafCertRequest.Generate(&healthySimpleStore)
+				generatedLeafCert, err := leafCertRequest.Generate(secretStore)
 				Expect(err).ToNot(HaveOccurred())
 				leafRecord := generatedLeafCert.(types.CertificateRecord)
 				Expect(leafRecord.Ca).To(Equal(caCert.(types.CertificateRecord).Certificate))
@@ -155,6 +159,156 @@ var _ = Describe("Certificates", func() {
 				Expect(leafCert.KeyUsage).To(Equal(x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature))
 			})
 		})
+
+		Context("certificate requests with a key type", func() {
+			It("should reject unknown key types", func() {
+				certificateRequest := types.CertificateRequest{
+					Name: "dsa_ca",
+					Type: types.CertificateType,
+					Parameters: types.CertificateParams{
+						IsCa:       true,
+						CommonName: "dsa",
+						KeyType:    "dsa",
+					},
+				}
+				Expect(certificateRequest.Validate()).To(BeFalse())
+			})
+			It("should sign leaf certs with whichever key algorithm the stored CA uses", func() {
+				for _, caKeyType := range []string{types.KeyTypeEcdsaP256, types.KeyTypeEcdsaP384, types.KeyTypeEd25519} {
+					var caName = "datCa-" + caKeyType
+					caReq := types.CertificateRequest{
+						Name: caName,
+						Type: types.CertificateType,
+						Parameters: types.CertificateParams{
+							IsCa:       true,
+							CommonName: "goinggoingbackbacktocaca",
+							KeyType:    caKeyType,
+						},
+					}
+					Expect(caReq.Validate()).To(BeTrue())
+					caCert, err := caReq.Generate(secretStore)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = caCert.Store(secretStore, caReq.Name)
+					Expect(err).ToNot(HaveOccurred())
+
+					leafCertRequest := types.CertificateRequest{
+						Name: "leaf",
+						Type: types.CertificateType,
+						Parameters: types.CertificateParams{
+							Ca:         caName,
+							CommonName: "Leafy",
+							KeyType:    types.KeyTypeEd25519,
+						},
+					}
+					generatedLeafCert, err := leafCertRequest.Generate(secretStore)
+					Expect(err).ToNot(HaveOccurred())
+					leafRecord := generatedLeafCert.(types.CertificateRecord)
+
+					caBlock, _ := pem.Decode([]byte(leafRecord.Ca))
+					parsedCa, err := x509.ParseCertificate(caBlock.Bytes)
+					Expect(err).ToNot(HaveOccurred())
+					leafBlock, _ := pem.Decode([]byte(leafRecord.Certificate))
+					leafCert, err := x509.ParseCertificate(leafBlock.Bytes)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(leafCert.CheckSignatureFrom(parsedCa)).To(Succeed())
+					Expect(leafCert.PublicKeyAlgorithm).To(Equal(x509.Ed25519))
+					Expect(leafCert.KeyUsage).To(Equal(x509.KeyUsageDigitalSignature))
+
+					keyBlock, _ := pem.Decode([]byte(leafRecord.PrivateKey))
+					Expect(keyBlock.Type).To(Equal("PRIVATE KEY"))
+					_, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+					Expect(err).ToNot(HaveOccurred())
+				}
+			})
+		})
+
+		Context("a CA with a transitional version", func() {
+			It("should sign with the active version and include the transitional version in the ca bundle", func() {
+				var caName = "rotatingCa"
+				activeCaReq := types.CertificateRequest{
+					Name: caName,
+					Type: types.CertificateType,
+					Parameters: types.CertificateParams{
+						IsCa:       true,
+						CommonName: "active",
+					},
+				}
+				activeCa, err := activeCaReq.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = activeCa.Store(secretStore, caName)
+				Expect(err).ToNot(HaveOccurred())
+
+				transitionalCaReq := activeCaReq
+				transitionalCaReq.Parameters.CommonName = "transitional"
+				transitionalCaReq.Parameters.Transitional = true
+				transitionalCa, err := transitionalCaReq.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(transitionalCa.(types.CertificateRecord).Transitional).To(BeTrue())
+				_, err = transitionalCa.Store(secretStore, caName)
+				Expect(err).ToNot(HaveOccurred())
+
+				leafCertRequest := types.CertificateRequest{
+					Name: "leaf",
+					Type: types.CertificateType,
+					Parameters: types.CertificateParams{
+						Ca:         caName,
+						CommonName: "Leafy",
+					},
+				}
+				generatedLeafCert, err := leafCertRequest.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				leafRecord := generatedLeafCert.(types.CertificateRecord)
+				Expect(leafRecord.Ca).To(Equal(activeCa.(types.CertificateRecord).Certificate + transitionalCa.(types.CertificateRecord).Certificate))
+
+				caBlock, _ := pem.Decode([]byte(activeCa.(types.CertificateRecord).Certificate))
+				parsedCa, err := x509.ParseCertificate(caBlock.Bytes)
+				Expect(err).ToNot(HaveOccurred())
+				leafBlock, _ := pem.Decode([]byte(leafRecord.Certificate))
+				leafCert, err := x509.ParseCertificate(leafBlock.Bytes)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(leafCert.CheckSignatureFrom(parsedCa)).To(Succeed())
+			})
+
+			It("should honor a transitional version older than the active one", func() {
+				var caName = "rotatedCa"
+				caReq := types.CertificateRequest{
+					Name: caName,
+					Type: types.CertificateType,
+					Parameters: types.CertificateParams{
+						IsCa:       true,
+						CommonName: "old",
+					},
+				}
+				oldCa, err := caReq.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				oldCaResponse, err := oldCa.Store(secretStore, caName)
+				Expect(err).ToNot(HaveOccurred())
+
+				caReq.Parameters.CommonName = "new"
+				newCa, err := caReq.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newCa.Store(secretStore, caName)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(types.SetTransitionalVersion(secretStore, caName, oldCaResponse.(types.CertificateResponse).Id)).To(Succeed())
+				leafCertRequest := types.CertificateRequest{
+					Name: "leaf",
+					Type: types.CertificateType,
+					Parameters: types.CertificateParams{
+						Ca:         caName,
+						CommonName: "Leafy",
+					},
+				}
+				generatedLeafCert, err := leafCertRequest.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generatedLeafCert.(types.CertificateRecord).Ca).To(Equal(newCa.(types.CertificateRecord).Certificate + oldCa.(types.CertificateRecord).Certificate))
+
+				Expect(types.SetTransitionalVersion(secretStore, caName, "")).To(Succeed())
+				generatedLeafCert, err = leafCertRequest.Generate(secretStore)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(generatedLeafCert.(types.CertificateRecord).Ca).To(Equal(newCa.(types.CertificateRecord).Certificate))
+			})
+		})
 	})
 
-})
+}
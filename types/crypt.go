@@ -0,0 +1,179 @@
+package types
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Bosh jobs consume the password_hash of user credentials directly in /etc/shadow style configuration files, so the
+// hash needs to be a standard SHA-512 crypt(3) string ($6$salt$hash). There is no implementation of this algorithm in
+// the standard library, this follows the reference specification: https://akkadia.org/drepper/SHA-crypt.txt
+
+const sha512CryptPrefix = "$6$"
+const sha512CryptRoundsPrefix = "rounds="
+const sha512CryptDefaultRounds = 5000
+const sha512CryptMinRounds = 1000
+const sha512CryptMaxRounds = 999999999
+const sha512CryptSaltLength = 16
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// The order in which bytes of the final digest are grouped before being base64 encoded, defined by the specification
+var sha512CryptByteOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+func sha512CryptSalt() (string, error) {
+	salt := make([]byte, sha512CryptSaltLength)
+	for i := range salt {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(cryptAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		salt[i] = cryptAlphabet[index.Int64()]
+	}
+	return string(salt), nil
+}
+
+func sha512Crypt(password string, salt string) string {
+	if len(salt) > sha512CryptSaltLength {
+		salt = salt[:sha512CryptSaltLength]
+	}
+	return sha512CryptPrefix + salt + "$" + sha512CryptHash(password, salt, sha512CryptDefaultRounds)
+}
+
+// Hashes the password like crypt(3) with a "$6$salt" or "$6$rounds=N$salt" setting, anything after the salt is ignored
+// so an existing hash can be passed as the setting to check a password against it
+func Sha512Crypt(password string, setting string) (string, error) {
+	if !strings.HasPrefix(setting, sha512CryptPrefix) {
+		return "", errors.New(fmt.Sprintf("setting must start with %s", sha512CryptPrefix))
+	}
+	salt := strings.TrimPrefix(setting, sha512CryptPrefix)
+
+	rounds := sha512CryptDefaultRounds
+	roundsSetting := ""
+	if strings.HasPrefix(salt, sha512CryptRoundsPrefix) {
+		end := strings.Index(salt, "$")
+		if end < 0 {
+			return "", errors.New("setting has rounds but no salt")
+		}
+		var err error
+		rounds, err = strconv.Atoi(strings.TrimPrefix(salt[:end], sha512CryptRoundsPrefix))
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("invalid rounds in setting: %s", err))
+		}
+		// out of range rounds are clamped rather than rejected, as specified
+		if rounds < sha512CryptMinRounds {
+			rounds = sha512CryptMinRounds
+		} else if rounds > sha512CryptMaxRounds {
+			rounds = sha512CryptMaxRounds
+		}
+		roundsSetting = sha512CryptRoundsPrefix + strconv.Itoa(rounds) + "$"
+		salt = salt[end+1:]
+	}
+
+	if end := strings.Index(salt, "$"); end >= 0 {
+		salt = salt[:end]
+	}
+	if len(salt) > sha512CryptSaltLength {
+		salt = salt[:sha512CryptSaltLength]
+	}
+	return sha512CryptPrefix + roundsSetting + salt + "$" + sha512CryptHash(password, salt, rounds), nil
+}
+
+func sha512CryptHash(password string, salt string, rounds int) string {
+	pass := []byte(password)
+	saltBytes := []byte(salt)
+
+	alternate := sha512.New()
+	alternate.Write(pass)
+	alternate.Write(saltBytes)
+	alternate.Write(pass)
+	alternateSum := alternate.Sum(nil)
+
+	digest := sha512.New()
+	digest.Write(pass)
+	digest.Write(saltBytes)
+	i := len(pass)
+	for ; i > sha512.Size; i -= sha512.Size {
+		digest.Write(alternateSum)
+	}
+	digest.Write(alternateSum[:i])
+	for i = len(pass); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			digest.Write(alternateSum)
+		} else {
+			digest.Write(pass)
+		}
+	}
+	digestSum := digest.Sum(nil)
+
+	passDigest := sha512.New()
+	for i = 0; i < len(pass); i++ {
+		passDigest.Write(pass)
+	}
+	pSequence := repeatToLength(passDigest.Sum(nil), len(pass))
+
+	saltDigest := sha512.New()
+	for i = 0; i < 16+int(digestSum[0]); i++ {
+		saltDigest.Write(saltBytes)
+	}
+	sSequence := repeatToLength(saltDigest.Sum(nil), len(saltBytes))
+
+	for i = 0; i < rounds; i++ {
+		round := sha512.New()
+		if i&1 != 0 {
+			round.Write(pSequence)
+		} else {
+			round.Write(digestSum)
+		}
+		if i%3 != 0 {
+			round.Write(sSequence)
+		}
+		if i%7 != 0 {
+			round.Write(pSequence)
+		}
+		if i&1 != 0 {
+			round.Write(digestSum)
+		} else {
+			round.Write(pSequence)
+		}
+		digestSum = round.Sum(nil)
+	}
+
+	var encoded strings.Builder
+	for _, group := range sha512CryptByteOrder {
+		encoded.WriteString(cryptBase64(digestSum[group[0]], digestSum[group[1]], digestSum[group[2]], 4))
+	}
+	encoded.WriteString(cryptBase64(0, 0, digestSum[63], 2))
+
+	return encoded.String()
+}
+
+func repeatToLength(sum []byte, length int) []byte {
+	sequence := make([]byte, 0, length)
+	for len(sequence) < length {
+		remaining := length - len(sequence)
+		if remaining > len(sum) {
+			remaining = len(sum)
+		}
+		sequence = append(sequence, sum[:remaining]...)
+	}
+	return sequence
+}
+
+func cryptBase64(b2, b1, b0 byte, n int) string {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	out := make([]byte, 0, n)
+	for ; n > 0; n-- {
+		out = append(out, cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+	return string(out)
+}
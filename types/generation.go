@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"strings"
+)
+
+// Generation requests are persisted in the store next to the credential they generated so that a credential can be
+// rotated later without the original request body. They live under their own prefix so they never collide with
+// credential names, each write records the id of the credential version that the request generated. The parameters only
+// apply to that version, a credential that was set since can't be regenerated from them.
+const GenerationRequestPrefix = "/bosh-vault/generation-requests"
+
+type RegenerateRequest struct {
+	Name              string `json:"name"`
+	SetAsTransitional bool   `json:"set_as_transitional,omitempty"`
+}
+
+func GenerationRequestName(name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return GenerationRequestPrefix + name
+}
+
+// Bookkeeping kept next to credentials, names under these prefixes can never be used through the api. The emulated
+// history of KV1 mounts is kept by the store itself but is just as off limits.
+var reservedPrefixes = []string{GenerationRequestPrefix, TransitionalVersionPrefix, store.KV1HistoryPrefix}
+
+func IsReservedName(name string) bool {
+	name = "/" + strings.TrimPrefix(name, "/")
+	for _, prefix := range reservedPrefixes {
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// The credential bookkeeping under a reserved name belongs to, any other name is a credential itself
+func ReservedCredentialName(name string) string {
+	name = "/" + strings.TrimPrefix(name, "/")
+	if historyName, ok := store.KV1HistoryCredentialName(name); ok {
+		return historyName
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(name, prefix+"/") {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+func StoreGenerationRequest(secretStore secret.Store, request CredentialGenerationRequest) error {
+	latest, err := secretStore.GetLatestByName(request.CredentialName())
+	if err != nil {
+		return err
+	}
+
+	// Generate fills in defaults on the request so the persisted parameters are exactly what was used
+	requestJson, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	_, err = secretStore.Set(GenerationRequestName(request.CredentialName()), map[string]interface{}{
+		"id":      latest.Id,
+		"request": string(requestJson),
+	})
+	return err
+}
+
+func GetGenerationRequest(secretStore secret.Store, name string) (CredentialGenerationRequest, error) {
+	storedRequest, err := secretStore.GetLatestByName(GenerationRequestName(name))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("no generation parameters found for %s, only generated credentials can be regenerated", name))
+	}
+
+	storedValue, ok := storedRequest.Value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("generation parameters for %s are malformed", name))
+	}
+	requestJson, ok := storedValue["request"].(string)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("generation parameters for %s are malformed", name))
+	}
+
+	latest, err := secretStore.GetLatestByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if generatedId, _ := storedValue["id"].(string); generatedId != latest.Id {
+		return nil, errors.New(fmt.Sprintf("%s was set since it was generated, only generated credentials can be regenerated", name))
+	}
+
+	request, _, err := ParseCredentialGenerationRequest([]byte(requestJson))
+	return request, err
+}
+
+func DeleteGenerationRequest(secretStore secret.Store, name string) error {
+	if !secretStore.Exists(GenerationRequestName(name)) {
+		return nil
+	}
+	return secretStore.DeleteByName(GenerationRequestName(name))
+}
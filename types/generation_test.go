@@ -0,0 +1,56 @@
+package types_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generation Requests", func() {
+	withEachStore(generationRequestSpecs)
+})
+
+func generationRequestSpecs() {
+	Describe("persistence", func() {
+		It("stores the parameters a credential was generated with so it can be regenerated", func() {
+			pr := &types.PasswordRequest{
+				Name: "/Director/deployment/regenerated_pass",
+				Type: types.PasswordType,
+				Parameters: types.PasswordParams{
+					Length:        12,
+					ExcludeNumber: true,
+				},
+			}
+			password, err := pr.Generate(secretStore)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = password.Store(secretStore, pr.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(types.StoreGenerationRequest(secretStore, pr)).To(Succeed())
+
+			storedRequest, err := types.GetGenerationRequest(secretStore, pr.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storedRequest).To(Equal(pr))
+			Expect(storedRequest.Validate()).To(BeTrue())
+		})
+		It("returns an error for credentials that were set after they were generated", func() {
+			pr := &types.PasswordRequest{
+				Name: "/Director/deployment/set_pass",
+				Type: types.PasswordType,
+			}
+			password, err := pr.Generate(secretStore)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = password.Store(secretStore, pr.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(types.StoreGenerationRequest(secretStore, pr)).To(Succeed())
+
+			_, err = secretStore.Set(pr.Name, map[string]interface{}{"value": "chosen"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = types.GetGenerationRequest(secretStore, pr.Name)
+			Expect(err).To(HaveOccurred())
+		})
+		It("returns an error for credentials that were never generated", func() {
+			_, err := types.GetGenerationRequest(secretStore, "/Director/deployment/never_generated")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
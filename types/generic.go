@@ -38,6 +38,21 @@ type CredentialRecordInterface interface {
 	Store(secretStore secret.Store, name string) (CredentialResponse, error)
 }
 
+// Returned by Store for values that can't be stored the way they were set
+type InvalidRecordError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidRecordError) Error() string {
+	return fmt.Sprintf("invalid value for %s: %s", e.Name, e.Reason)
+}
+
+func IsInvalidRecord(err error) bool {
+	_, invalid := err.(*InvalidRecordError)
+	return invalid
+}
+
 type CredentialGenerationRequest interface {
 	Generate(secretStore secret.Store) (CredentialRecordInterface, error)
 	Validate() bool
@@ -66,11 +81,21 @@ func ParseCredentialSetRequest(requestBody []byte) (CredentialSetRequest, error)
 		record = &SshKeypairRecord{}
 	case RsaKeypairType:
 		record = &RsaKeypairRecord{}
+	case UserType:
+		record = &UserRecord{}
+	case ValueType:
+		record = &ValueRecord{}
+	case JsonType:
+		record = &JsonRecord{}
 	default:
-		return CredentialSetRequest{}, errors.New(fmt.Sprintf("credential set request type: %s not supported! Must be one of: %s, %s, %s, %s", g.Type, CertificateType, PasswordType, SshKeypairType, RsaKeypairType))
+		return CredentialSetRequest{}, errors.New(fmt.Sprintf("credential set request type: %s not supported! Must be one of: %s, %s, %s, %s, %s, %s, %s", g.Type, CertificateType, PasswordType, SshKeypairType, RsaKeypairType, UserType, ValueType, JsonType))
 	}
 
 	err = json.Unmarshal(g.Value, &record)
+	// a null value unmarshals to a nil record which can't be stored
+	if err == nil && record == nil {
+		err = errors.New(fmt.Sprintf("credential set request for %s is missing a value", g.Name))
+	}
 
 	return CredentialSetRequest{
 		Name:   g.Name,
@@ -97,8 +122,10 @@ func ParseCredentialGenerationRequest(requestBody []byte) (req CredentialGenerat
 		req = &SshKeypairRequest{}
 	case RsaKeypairType:
 		req = &RsaKeypairRequest{}
+	case UserType:
+		req = &UserRequest{}
 	default:
-		return nil, noOverwrite, errors.New(fmt.Sprintf("credential request type: %s not supported! Must be one of: %s, %s, %s, %s", g.Type, CertificateType, PasswordType, SshKeypairType, RsaKeypairType))
+		return nil, noOverwrite, errors.New(fmt.Sprintf("credential request type: %s not supported! Must be one of: %s, %s, %s, %s, %s", g.Type, CertificateType, PasswordType, SshKeypairType, RsaKeypairType, UserType))
 	}
 
 	err = json.Unmarshal(requestBody, &req)
@@ -54,5 +54,14 @@ var _ = Describe("Generic", func() {
 				Expect(credential).To(BeAssignableToTypeOf((*types.RsaKeypairRequest)(nil)))
 			})
 		})
+
+		Context("valid user requests", func() {
+			It("parses a user request object", func() {
+				credential, noOverwrite, err := types.ParseCredentialGenerationRequest([]byte(fakes.UserPostRequestBody))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noOverwrite).To(BeFalse())
+				Expect(credential).To(BeAssignableToTypeOf((*types.UserRequest)(nil)))
+			})
+		})
 	})
 })
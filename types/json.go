@@ -0,0 +1,35 @@
+package types
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+)
+
+const JsonType = "json"
+
+// JsonRecords hold an arbitrary operator provided JSON object and can only be set, never generated
+type JsonRecord map[string]interface{}
+
+type JsonResponse struct {
+	Name  string     `json:"name"`
+	Id    string     `json:"id"`
+	Value JsonRecord `json:"value"`
+}
+
+func (record JsonRecord) Store(secretStore secret.Store, name string) (CredentialResponse, error) {
+	var resp JsonResponse
+	// the object is always nested under "value" so that it can't be confused with the fields of other credential types
+	id, err := secretStore.Set(name, map[string]interface{}{
+		"value": map[string]interface{}(record),
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	resp = JsonResponse{
+		Name:  name,
+		Id:    id,
+		Value: record,
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,39 @@
+package types_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	fakes "github.com/cloudfoundry-community/bosh-vault/types/typesfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON", func() {
+	withEachStore(jsonSpecs)
+})
+
+func jsonSpecs() {
+	Describe("set request parsing", func() {
+		It("parses a json object", func() {
+			setRequest, err := types.ParseCredentialSetRequest([]byte(fakes.JsonPutRequestBody))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(setRequest.Type).To(Equal(types.JsonType))
+			record := *setRequest.Record.(*types.JsonRecord)
+			Expect(record["value"]).To(Equal("nested value key"))
+			Expect(record["feature_flags"]).To(HaveKey("rollout"))
+		})
+	})
+	Describe("storage", func() {
+		It("nests the object under value so it can't be confused with other credential fields", func() {
+			setRequest, err := types.ParseCredentialSetRequest([]byte(fakes.JsonPutRequestBody))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = setRequest.Record.Store(secretStore, setRequest.Name)
+			Expect(err).ToNot(HaveOccurred())
+
+			stored, err := secretStore.GetLatestByName(setRequest.Name)
+			Expect(err).ToNot(HaveOccurred())
+			storedValue := stored.Value.(map[string]interface{})
+			Expect(storedValue).To(HaveLen(1))
+			Expect(storedValue["value"]).To(HaveKeyWithValue("value", "nested value key"))
+		})
+	})
+}
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const KeyTypeRsa = "rsa"
+const KeyTypeEcdsaP256 = "ecdsa-p256"
+const KeyTypeEcdsaP384 = "ecdsa-p384"
+const KeyTypeEd25519 = "ed25519"
+
+const pkcs8PemType = "PRIVATE KEY"
+const rsaPemType = "RSA PRIVATE KEY"
+
+// Shared by the key pair credential types, an empty key_type always means RSA to stay compatible with older requests
+type KeypairParams struct {
+	KeyType string `json:"key_type,omitempty"`
+}
+
+func validKeyType(keyType string) bool {
+	switch keyType {
+	case "", KeyTypeRsa, KeyTypeEcdsaP256, KeyTypeEcdsaP384, KeyTypeEd25519:
+		return true
+	default:
+		return false
+	}
+}
+
+func generatePrivateKey(keyType string, rsaKeyBits int) (crypto.Signer, error) {
+	var privateKey crypto.Signer
+	var err error
+
+	switch keyType {
+	case "", KeyTypeRsa:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case KeyTypeEcdsaP256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeEcdsaP384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported key type: %s", keyType))
+	}
+
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Problem generating %s keypair: %s", keyType, err))
+	}
+	return privateKey, nil
+}
+
+// RSA keys keep their historical PKCS#1 encoding (under the pem type passed in) so existing consumers don't break, every
+// other key type is PKCS#8 encoded since that is the only format the standard library can marshal for all of them
+func marshalPrivateKeyPem(privateKey crypto.Signer, rsaPemBlockType string) ([]byte, error) {
+	if rsaKey, ok := privateKey.(*rsa.PrivateKey); ok {
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  rsaPemBlockType,
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+		}), nil
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pkcs8PemType,
+		Bytes: keyBytes,
+	}), nil
+}
+
+func marshalPublicKeyPem(publicKey crypto.PublicKey) ([]byte, error) {
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubKeyBytes,
+	}), nil
+}
+
+// Keys may have been generated by any version of bosh-vault or set by an operator so try PKCS#8 first and fall back to
+// the key specific formats, the pem block type isn't trusted since older ssh keys were PKCS#1 under "PRIVATE KEY"
+func parsePrivateKeyPem(pemData string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("unable to decode private key pem")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported PKCS#8 private key type")
+		}
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("unable to parse %s, expected a PKCS#8, PKCS#1, or EC private key", block.Type))
+}
+
+func generateSubjectKeyId(publicKey crypto.PublicKey) ([]byte, error) {
+	subjectKeyHash := sha1.New()
+	// RSA subject key ids have always been the hash of the modulus, keep that so ids of regenerated certs are stable
+	if rsaKey, ok := publicKey.(*rsa.PublicKey); ok {
+		subjectKeyHash.Write(rsaKey.N.Bytes())
+		return subjectKeyHash.Sum(nil), nil
+	}
+
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	subjectKeyHash.Write(pubKeyBytes)
+	return subjectKeyHash.Sum(nil), nil
+}
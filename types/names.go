@@ -0,0 +1,14 @@
+package types
+
+import "strings"
+
+// Names with "." or ".." segments could refer to a different credential than the one policies and the api checked, so
+// they are never accepted
+func HasRelativeSegments(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -43,25 +43,25 @@ func (record PasswordRecord) Store(secretStore secret.Store, name string) (Crede
 	return respObj, nil
 }
 
-func (r *PasswordRequest) numAlphabets() int {
+func (p PasswordParams) numAlphabets() int {
 	alphabets := 4
-	if r.Parameters.ExcludeLower {
+	if p.ExcludeLower {
 		alphabets -= 1
 	}
-	if r.Parameters.ExcludeUpper {
+	if p.ExcludeUpper {
 		alphabets -= 1
 	}
-	if r.Parameters.ExcludeNumber {
+	if p.ExcludeNumber {
 		alphabets -= 1
 	}
-	if !r.Parameters.IncludeSpecial {
+	if !p.IncludeSpecial {
 		alphabets -= 1
 	}
 	return alphabets
 }
 
 func (r *PasswordRequest) Validate() bool {
-	return r.Type == PasswordType && r.numAlphabets() > 0
+	return r.Type == PasswordType && r.Parameters.numAlphabets() > 0
 }
 
 func (r *PasswordRequest) Generate(secretStore secret.Store) (CredentialRecordInterface, error) {
@@ -70,21 +70,30 @@ func (r *PasswordRequest) Generate(secretStore secret.Store) (CredentialRecordIn
 		r.Parameters.Length = PasswordDefaultLength
 	}
 
+	passValue, err := generatePassword(r.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return PasswordRecord(passValue), nil
+}
+
+func generatePassword(params PasswordParams) (string, error) {
 	passwordAlphabet := ""
 
-	if !r.Parameters.ExcludeNumber {
+	if !params.ExcludeNumber {
 		passwordAlphabet += gopass.Digits
 	}
 
-	if !r.Parameters.ExcludeUpper {
+	if !params.ExcludeUpper {
 		passwordAlphabet += gopass.UpperLetters
 	}
 
-	if !r.Parameters.ExcludeLower {
+	if !params.ExcludeLower {
 		passwordAlphabet += gopass.LowerLetters
 	}
 
-	if r.Parameters.IncludeSpecial {
+	if params.IncludeSpecial {
 		passwordAlphabet += gopass.Symbols
 	}
 
@@ -94,18 +103,16 @@ func (r *PasswordRequest) Generate(secretStore secret.Store) (CredentialRecordIn
 	generator, err := gopass.NewGenerator(&gopass.GeneratorInput{
 		LowerLetters: passwordAlphabet,
 	})
+	if err != nil {
+		return "", err
+	}
 
 	// We overload the generator input to avoid length parsing sadness
 	// because we build up the entire alphabet in "lower letters" we
 	// can tell generate we want no digits, no symbols, and no uppercase letters
 	// We prefer this generation library because it is the one used by the existing
 	// password generation Vault plugin and maintained by people at Hashicorp
-	passValue, err := generator.Generate(r.Parameters.Length, 0, 0, true, true)
-	if err != nil {
-		return nil, err
-	}
-
-	return PasswordRecord(passValue), nil
+	return generator.Generate(params.Length, 0, 0, true, true)
 }
 
 func (r *PasswordRequest) CredentialType() string {
@@ -1,19 +1,17 @@
 package types
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
 )
 
 const RsaKeypairType = "rsa"
 const RsaKeySizeBits = 2048
 
+// Despite the type name these are PEM encoded key pairs, key_type can be passed to generate something other than RSA
 type RsaKeypairRequest struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Parameters KeypairParams `json:"parameters"`
 }
 
 func (record RsaKeypairRecord) Store(secretStore secret.Store, name string) (CredentialResponse, error) {
@@ -37,28 +35,24 @@ func (record RsaKeypairRecord) Store(secretStore secret.Store, name string) (Cre
 }
 
 func (r *RsaKeypairRequest) Validate() bool {
-	return r.Type == RsaKeypairType
+	return r.Type == RsaKeypairType && validKeyType(r.Parameters.KeyType)
 }
 
 func (r *RsaKeypairRequest) Generate(secretStore secret.Store) (CredentialRecordInterface, error) {
-	privKey, err := rsa.GenerateKey(rand.Reader, RsaKeySizeBits)
+	privKey, err := generatePrivateKey(r.Parameters.KeyType, RsaKeySizeBits)
 	if err != nil {
 		return nil, err
 	}
 
-	pemPriv := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privKey),
-	})
+	pemPriv, err := marshalPrivateKeyPem(privKey, rsaPemType)
+	if err != nil {
+		return nil, err
+	}
 
-	pubKeyBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+	pemPublic, err := marshalPublicKeyPem(privKey.Public())
 	if err != nil {
 		return nil, err
 	}
-	pemPublic := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: pubKeyBytes,
-	})
 
 	rsaKeyPair := RsaKeypairRecord{
 		PublicKey:  string(pemPublic),
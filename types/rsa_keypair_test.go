@@ -1,6 +1,7 @@
 package types_test
 
 import (
+	"crypto/ecdsa"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -47,6 +48,21 @@ var _ = Describe("RSA", func() {
 
 				Expect(string(pemPublic)).To(Equal(rsaRecord.PublicKey))
 			})
+
+			It("generates a PKCS#8 encoded key when a key type is passed", func() {
+				ecdsaRequest := RsaRequest
+				ecdsaRequest.Parameters.KeyType = types.KeyTypeEcdsaP256
+				Expect(ecdsaRequest.Validate()).To(BeTrue())
+				keypair, err := ecdsaRequest.Generate(&store.SimpleStore{})
+				Expect(err).ToNot(HaveOccurred())
+				keypairRecord := keypair.(types.RsaKeypairRecord)
+
+				privBlock, _ := pem.Decode([]byte(keypairRecord.PrivateKey))
+				Expect(privBlock.Type).To(Equal("PRIVATE KEY"))
+				ecPriv, err := x509.ParsePKCS8PrivateKey(privBlock.Bytes)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ecPriv).To(BeAssignableToTypeOf(&ecdsa.PrivateKey{}))
+			})
 		})
 	})
 })
@@ -1,19 +1,28 @@
 package types
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
+	"encoding/binary"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"github.com/cloudfoundry-community/bosh-vault/secret"
 	"golang.org/x/crypto/ssh"
+	"math/big"
 )
 
 const SshKeypairType = "ssh"
+const openSshPemType = "OPENSSH PRIVATE KEY"
+const openSshKeyMagic = "openssh-key-v1\x00"
 
 type SshKeypairRequest struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Parameters KeypairParams `json:"parameters"`
 }
 
 func (record SshKeypairRecord) Store(secretStore secret.Store, name string) (CredentialResponse, error) {
@@ -37,22 +46,22 @@ func (record SshKeypairRecord) Store(secretStore secret.Store, name string) (Cre
 }
 
 func (r *SshKeypairRequest) Validate() bool {
-	return r.Type == SshKeypairType
+	return r.Type == SshKeypairType && validKeyType(r.Parameters.KeyType)
 }
 
 func (r *SshKeypairRequest) Generate(secretStore secret.Store) (CredentialRecordInterface, error) {
 
-	privKey, err := rsa.GenerateKey(rand.Reader, RsaKeySizeBits)
+	privKey, err := generatePrivateKey(r.Parameters.KeyType, RsaKeySizeBits)
 	if err != nil {
 		return nil, err
 	}
 
-	pemPriv := pem.EncodeToMemory(&pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privKey),
-	})
+	pubKey, err := ssh.NewPublicKey(privKey.Public())
+	if err != nil {
+		return nil, err
+	}
 
-	pubKey, err := ssh.NewPublicKey(&privKey.PublicKey)
+	pemPriv, err := marshalSshPrivateKeyPem(privKey, pubKey)
 	if err != nil {
 		return nil, err
 	}
@@ -87,3 +96,74 @@ type SshKeypairResponse struct {
 	Id    string           `json:"id"`
 	Value SshKeypairRecord `json:"value"`
 }
+
+// ssh RSA keys have always been PKCS#1 under a "PRIVATE KEY" block, keep that for anything already consuming them. Every
+// other key type is written in the OpenSSH private key format, the only one ssh tooling reads for ed25519 keys
+func marshalSshPrivateKeyPem(privateKey crypto.Signer, publicKey ssh.PublicKey) ([]byte, error) {
+	var keyFields []byte
+	switch key := privateKey.(type) {
+	case ed25519.PrivateKey:
+		keyFields = ssh.Marshal(struct {
+			Pub  []byte
+			Priv []byte
+		}{
+			Pub:  key.Public().(ed25519.PublicKey),
+			Priv: key,
+		})
+	case *ecdsa.PrivateKey:
+		keyFields = ssh.Marshal(struct {
+			Curve string
+			Pub   []byte
+			D     *big.Int
+		}{
+			Curve: "nistp" + fmt.Sprint(key.Curve.Params().BitSize),
+			Pub:   elliptic.Marshal(key.Curve, key.X, key.Y),
+			D:     key.D,
+		})
+	default:
+		return marshalPrivateKeyPem(privateKey, pkcs8PemType)
+	}
+
+	checkBytes := make([]byte, 4)
+	if _, err := rand.Read(checkBytes); err != nil {
+		return nil, errors.New(fmt.Sprintf("Problem generating ssh private key check: %s", err))
+	}
+	check := binary.BigEndian.Uint32(checkBytes)
+
+	privateBlock := ssh.Marshal(struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Rest    []byte `ssh:"rest"`
+	}{
+		Check1:  check,
+		Check2:  check,
+		Keytype: publicKey.Type(),
+		Rest:    keyFields,
+	})
+	// the empty comment, then padding to the cipher block size which is 8 for the "none" cipher
+	privateBlock = append(privateBlock, ssh.Marshal(struct{ Comment string }{})...)
+	for i := byte(1); len(privateBlock)%8 != 0; i++ {
+		privateBlock = append(privateBlock, i)
+	}
+
+	keyBytes := append([]byte(openSshKeyMagic), ssh.Marshal(struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       publicKey.Marshal(),
+		PrivKeyBlock: privateBlock,
+	})...)
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  openSshPemType,
+		Bytes: keyBytes,
+	}), nil
+}
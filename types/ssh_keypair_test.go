@@ -42,6 +42,26 @@ var _ = Describe("SSH", func() {
 				Expect(string(pubKeyBytes)).To(Equal(cred.(types.SshKeypairRecord).PublicKey))
 				Expect(ssh.FingerprintLegacyMD5(pubKey)).To(Equal(cred.(types.SshKeypairRecord).PublicKeyFingerprint))
 			})
+
+			It("generates ecdsa and ed25519 ssh keys", func() {
+				for keyType, algorithm := range map[string]string{
+					types.KeyTypeEcdsaP256: ssh.KeyAlgoECDSA256,
+					types.KeyTypeEcdsaP384: ssh.KeyAlgoECDSA384,
+					types.KeyTypeEd25519:   ssh.KeyAlgoED25519,
+				} {
+					keyTypeRequest := SshRequest
+					keyTypeRequest.Parameters.KeyType = keyType
+					Expect(keyTypeRequest.Validate()).To(BeTrue())
+					cred, err := keyTypeRequest.Generate(&store.SimpleStore{})
+					Expect(err).ToNot(HaveOccurred())
+					privKeyPem, _ := pem.Decode([]byte(cred.(types.SshKeypairRecord).PrivateKey))
+					Expect(privKeyPem.Type).To(Equal("OPENSSH PRIVATE KEY"))
+					privKey, err := ssh.ParsePrivateKey([]byte(cred.(types.SshKeypairRecord).PrivateKey))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(privKey.PublicKey().Type()).To(Equal(algorithm))
+					Expect(string(ssh.MarshalAuthorizedKey(privKey.PublicKey()))).To(Equal(cred.(types.SshKeypairRecord).PublicKey))
+				}
+			})
 		})
 	})
 })
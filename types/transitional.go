@@ -0,0 +1,79 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+	"strings"
+)
+
+// Versions in the store can't be changed once written, so which version of a CA is transitional is recorded next to the
+// CA under its own prefix. At most one version is transitional, every other version is a candidate for signing.
+const TransitionalVersionPrefix = "/bosh-vault/transitional-versions"
+
+// Marks the version with the id as the transitional version of the CA, an empty version unmarks it
+type TransitionalVersionRequest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type TransitionalVersionResponse struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func TransitionalVersionName(name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return TransitionalVersionPrefix + name
+}
+
+func SetTransitionalVersion(secretStore secret.Store, name string, versionId string) error {
+	if versionId != "" {
+		caVersions, err := secretStore.GetByName(name)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, caVersion := range caVersions {
+			if caVersion.Id != versionId {
+				continue
+			}
+			if _, err := parseCertificateValue(caVersion.Value); err != nil {
+				return errors.New(fmt.Sprintf("version %s of %s is not a certificate", versionId, name))
+			}
+			found = true
+			break
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("%s has no version %s", name, versionId))
+		}
+	}
+
+	_, err := secretStore.Set(TransitionalVersionName(name), map[string]interface{}{
+		"id": versionId,
+	})
+	return err
+}
+
+// The id of the transitional version of the CA, empty when none is marked
+func GetTransitionalVersion(secretStore secret.Store, name string) string {
+	if !secretStore.Exists(TransitionalVersionName(name)) {
+		return ""
+	}
+	stored, err := secretStore.GetLatestByName(TransitionalVersionName(name))
+	if err != nil {
+		return ""
+	}
+	storedValue, _ := stored.Value.(map[string]interface{})
+	versionId, _ := storedValue["id"].(string)
+	return versionId
+}
+
+func DeleteTransitionalVersion(secretStore secret.Store, name string) error {
+	if !secretStore.Exists(TransitionalVersionName(name)) {
+		return nil
+	}
+	return secretStore.DeleteByName(TransitionalVersionName(name))
+}
@@ -1,25 +1,53 @@
 package types_test
 
 import (
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
 	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/store/storetest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
 	"net"
 
 	"testing"
 )
 
 var healthySimpleStore store.SimpleStore
+var memoryStore *store.MemoryStore
+
+// The store of the backend the current spec runs against, see withEachStore
+var secretStore secret.Store
 
 func TestTypes(t *testing.T) {
 	RegisterFailHandler(Fail)
+	logger.Log = logrus.New()
+	logger.Log.Out = ioutil.Discard
 
 	var err error
 	var listener1 net.Listener
-	healthySimpleStore, listener1, err = store.TestHealthySimpleStore(t)
+	healthySimpleStore, listener1, err = storetest.TestHealthySimpleStore(t)
 	Expect(err).NotTo(HaveOccurred())
+	memoryStore = store.NewMemoryStore()
 
 	RunSpecs(t, "Types Suite")
 
 	listener1.Close()
 }
+
+// Runs the specs against a Vault KV2 mount and against the memory store
+func withEachStore(body func()) {
+	Context("with a Vault KV2 store", func() {
+		BeforeEach(func() {
+			secretStore = &healthySimpleStore
+		})
+		body()
+	})
+	Context("with the memory store", func() {
+		BeforeEach(func() {
+			secretStore = memoryStore
+		})
+		body()
+	})
+}
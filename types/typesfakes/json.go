@@ -0,0 +1,12 @@
+package typesfakes
+
+const JsonPutRequestBody = `
+{
+  "name": "my_json",
+  "type": "json",
+  "value": {
+    "value": "nested value key",
+    "feature_flags": {"enabled": true, "rollout": 50}
+  }
+}
+`
@@ -0,0 +1,22 @@
+package typesfakes
+
+const UserPostRequestBody = `
+{
+  "name": "my_user",
+  "type": "user",
+  "parameters": {
+    "username": "bosh"
+  }
+}
+`
+
+const UserPutRequestBody = `
+{
+  "name": "my_user",
+  "type": "user",
+  "value": {
+    "username": "bosh",
+    "password": "Hello world!"
+  }
+}
+`
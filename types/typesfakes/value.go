@@ -0,0 +1,17 @@
+package typesfakes
+
+const ValuePutRequestBody = `
+{
+  "name": "my_value",
+  "type": "value",
+  "value": 12345678901234567890
+}
+`
+
+const ValueObjectPutRequestBody = `
+{
+  "name": "my_value",
+  "type": "value",
+  "value": {"not": "a scalar"}
+}
+`
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+)
+
+const UserType = "user"
+const UserDefaultUsernameLength = 20
+
+type UserRequest struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Parameters UserParams `json:"parameters"`
+}
+
+// User credentials accept the same parameters as passwords (which only apply to the generated password) as well as an
+// optional username, if no username is passed one will be generated
+type UserParams struct {
+	Username string `json:"username"`
+	PasswordParams
+}
+
+type UserRecord struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"password_hash"`
+}
+
+type UserResponse struct {
+	Name  string     `json:"name"`
+	Id    string     `json:"id"`
+	Value UserRecord `json:"value"`
+}
+
+func (record UserRecord) Store(secretStore secret.Store, name string) (CredentialResponse, error) {
+	var resp UserResponse
+
+	// users set through the api only need to pass a username and password, the hash is derived from the password
+	if record.PasswordHash == "" {
+		salt, err := sha512CryptSalt()
+		if err != nil {
+			return resp, err
+		}
+		record.PasswordHash = sha512Crypt(record.Password, salt)
+	} else {
+		// a hash of a different password would let the user log in with something other than the stored password
+		passwordHash, err := Sha512Crypt(record.Password, record.PasswordHash)
+		if err != nil || passwordHash != record.PasswordHash {
+			return resp, &InvalidRecordError{Name: name, Reason: "password_hash is not the sha512 crypt hash of password"}
+		}
+	}
+
+	secretId, err := secretStore.Set(name, map[string]interface{}{
+		"username":      record.Username,
+		"password":      record.Password,
+		"password_hash": record.PasswordHash,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	resp = UserResponse{
+		Name:  name,
+		Id:    secretId,
+		Value: record,
+	}
+
+	return resp, nil
+}
+
+func (r *UserRequest) Validate() bool {
+	return r.Type == UserType && r.Parameters.numAlphabets() > 0
+}
+
+func (r *UserRequest) Generate(secretStore secret.Store) (CredentialRecordInterface, error) {
+	if r.Parameters.Length == 0 {
+		r.Parameters.Length = PasswordDefaultLength
+	}
+
+	username := r.Parameters.Username
+	if username == "" {
+		var err error
+		// generated usernames are letters only so they are safe to use anywhere a username is expected
+		username, err = generatePassword(PasswordParams{
+			Length:        UserDefaultUsernameLength,
+			ExcludeNumber: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	password, err := generatePassword(r.Parameters.PasswordParams)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := sha512CryptSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	return UserRecord{
+		Username:     username,
+		Password:     password,
+		PasswordHash: sha512Crypt(password, salt),
+	}, nil
+}
+
+func (r *UserRequest) CredentialType() string {
+	return r.Type
+}
+
+func (r *UserRequest) CredentialName() string {
+	return r.Name
+}
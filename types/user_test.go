@@ -0,0 +1,144 @@
+package types_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	fakes "github.com/cloudfoundry-community/bosh-vault/types/typesfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+	"unicode"
+)
+
+var _ = Describe("User", func() {
+	withEachStore(userSpecs)
+})
+
+func userSpecs() {
+	Describe("request validation", func() {
+		Context("a valid user post request", func() {
+			var (
+				UserRequest types.UserRequest
+			)
+
+			BeforeEach(func() {
+				err := json.Unmarshal([]byte(fakes.UserPostRequestBody), &UserRequest)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns true when Validate is called", func() {
+				Expect(UserRequest.Validate()).To(BeTrue())
+			})
+
+			It("honors the username parameter", func() {
+				cr, err := UserRequest.Generate(&store.SimpleStore{})
+				Expect(err).ToNot(HaveOccurred())
+				userRecord := cr.(types.UserRecord)
+				Expect(userRecord.Username).To(Equal("bosh"))
+				Expect(len(userRecord.Password)).To(Equal(types.PasswordDefaultLength))
+				Expect(userRecord.PasswordHash).To(HavePrefix("$6$"))
+			})
+		})
+	})
+	Describe("generation", func() {
+		Context("parameter usage", func() {
+			It("generates a username when one isn't passed", func() {
+				ur := types.UserRequest{
+					Name: "some_user",
+					Type: types.UserType,
+					Parameters: types.UserParams{
+						PasswordParams: types.PasswordParams{
+							Length:        40,
+							ExcludeNumber: true,
+						},
+					},
+				}
+				cr, err := ur.Generate(&store.SimpleStore{})
+				Expect(err).ToNot(HaveOccurred())
+				userRecord := cr.(types.UserRecord)
+				Expect(len(userRecord.Username)).To(Equal(types.UserDefaultUsernameLength))
+				for _, char := range userRecord.Username {
+					Expect(unicode.IsLetter(char)).To(BeTrue())
+				}
+				Expect(len(userRecord.Password)).To(Equal(40))
+				for _, char := range userRecord.Password {
+					Expect(unicode.IsNumber(char)).To(BeFalse())
+				}
+			})
+			It("salts every password hash", func() {
+				ur := types.UserRequest{
+					Name: "some_user",
+					Type: types.UserType,
+				}
+				first, err := ur.Generate(&store.SimpleStore{})
+				Expect(err).ToNot(HaveOccurred())
+				second, err := ur.Generate(&store.SimpleStore{})
+				Expect(err).ToNot(HaveOccurred())
+				firstSalt := strings.Split(first.(types.UserRecord).PasswordHash, "$")[2]
+				secondSalt := strings.Split(second.(types.UserRecord).PasswordHash, "$")[2]
+				Expect(firstSalt).ToNot(Equal(secondSalt))
+			})
+		})
+	})
+	Describe("password hashes", func() {
+		It("matches the SHA-512 crypt reference vectors", func() {
+			// from the specification, https://akkadia.org/drepper/SHA-crypt.txt
+			for _, vector := range []struct {
+				password string
+				setting  string
+				hash     string
+			}{
+				{"Hello world!", "$6$saltstring", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+				{"Hello world!", "$6$rounds=10000$saltstringsaltstring", "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+				{"This is just a test", "$6$rounds=5000$toolongsaltstring", "$6$rounds=5000$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0"},
+				{"the minimum number is still observed", "$6$rounds=10$roundstoolow", "$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX."},
+			} {
+				hash, err := types.Sha512Crypt(vector.password, vector.setting)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hash).To(Equal(vector.hash))
+			}
+
+			_, err := types.Sha512Crypt("Hello world!", "$1$saltstring")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("hashes generated passwords with the default rounds", func() {
+			ur := types.UserRequest{
+				Name: "some_user",
+				Type: types.UserType,
+			}
+			cr, err := ur.Generate(&store.SimpleStore{})
+			Expect(err).ToNot(HaveOccurred())
+			userRecord := cr.(types.UserRecord)
+			hash, err := types.Sha512Crypt(userRecord.Password, userRecord.PasswordHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(userRecord.PasswordHash))
+		})
+	})
+	Describe("storage", func() {
+		It("derives a password hash for users set without one", func() {
+			setRequest, err := types.ParseCredentialSetRequest([]byte(fakes.UserPutRequestBody))
+			Expect(err).ToNot(HaveOccurred())
+			resp, err := setRequest.Record.Store(secretStore, setRequest.Name)
+			Expect(err).ToNot(HaveOccurred())
+			userRecord := resp.(types.UserResponse).Value
+			Expect(userRecord.Username).To(Equal("bosh"))
+			Expect(userRecord.Password).To(Equal("Hello world!"))
+			Expect(userRecord.PasswordHash).To(HavePrefix("$6$"))
+		})
+
+		It("only stores password hashes of the password", func() {
+			passwordHash := "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"
+			resp, err := types.UserRecord{Username: "bosh", Password: "Hello world!", PasswordHash: passwordHash}.Store(secretStore, "/hashed_user")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.(types.UserResponse).Value.PasswordHash).To(Equal(passwordHash))
+
+			_, err = types.UserRecord{Username: "bosh", Password: "Goodbye world!", PasswordHash: passwordHash}.Store(secretStore, "/mismatched_user")
+			Expect(types.IsInvalidRecord(err)).To(BeTrue())
+			_, err = types.UserRecord{Username: "bosh", Password: "Hello world!", PasswordHash: "not a hash"}.Store(secretStore, "/mismatched_user")
+			Expect(types.IsInvalidRecord(err)).To(BeTrue())
+			Expect(secretStore.Exists("/mismatched_user")).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/cloudfoundry-community/bosh-vault/secret"
+)
+
+const ValueType = "value"
+
+// ValueRecords hold a single operator provided JSON scalar (string, number, or boolean) and can only be set, never generated
+type ValueRecord struct {
+	Value interface{}
+}
+
+type ValueResponse struct {
+	Name  string      `json:"name"`
+	Id    string      `json:"id"`
+	Value interface{} `json:"value"`
+}
+
+func (record *ValueRecord) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep numbers as they were sent, a float64 would lose precision on large integers
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	switch value.(type) {
+	case string, json.Number, bool:
+		record.Value = value
+		return nil
+	default:
+		return errors.New("value credentials must be a JSON string, number, or boolean, use the json type for objects")
+	}
+}
+
+func (record ValueRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(record.Value)
+}
+
+func (record ValueRecord) Store(secretStore secret.Store, name string) (CredentialResponse, error) {
+	var resp ValueResponse
+	id, err := secretStore.Set(name, map[string]interface{}{
+		"value": record.Value,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	resp = ValueResponse{
+		Name:  name,
+		Id:    id,
+		Value: record.Value,
+	}
+
+	return resp, nil
+}
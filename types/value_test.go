@@ -0,0 +1,41 @@
+package types_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	fakes "github.com/cloudfoundry-community/bosh-vault/types/typesfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Value", func() {
+	withEachStore(valueSpecs)
+})
+
+func valueSpecs() {
+	Describe("set request parsing", func() {
+		It("parses a scalar value without losing precision", func() {
+			setRequest, err := types.ParseCredentialSetRequest([]byte(fakes.ValuePutRequestBody))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(setRequest.Type).To(Equal(types.ValueType))
+			Expect(setRequest.Record.(*types.ValueRecord).Value).To(Equal(json.Number("12345678901234567890")))
+		})
+		It("rejects objects", func() {
+			_, err := types.ParseCredentialSetRequest([]byte(fakes.ValueObjectPutRequestBody))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("storage", func() {
+		It("stores and returns the scalar value", func() {
+			setRequest, err := types.ParseCredentialSetRequest([]byte(fakes.ValuePutRequestBody))
+			Expect(err).ToNot(HaveOccurred())
+			resp, err := setRequest.Record.Store(secretStore, setRequest.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.(types.ValueResponse).Value).To(Equal(json.Number("12345678901234567890")))
+
+			stored, err := secretStore.GetLatestByName(setRequest.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored.Value.(map[string]interface{})["value"]).To(Equal(json.Number("12345678901234567890")))
+		})
+	})
+}
@@ -0,0 +1,19 @@
+package types
+
+// Identifies versions of a secret to undelete or destroy, version numbers are the ones reported by the versions endpoint
+type VersionsRequest struct {
+	Name     string `json:"name"`
+	Versions []int  `json:"versions"`
+}
+
+func (r VersionsRequest) Validate() bool {
+	if r.Name == "" || len(r.Versions) == 0 {
+		return false
+	}
+	for _, version := range r.Versions {
+		if version < 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package types_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Versions Requests", func() {
+	It("requires a name and at least one version", func() {
+		Expect(types.VersionsRequest{Name: "/some/password", Versions: []int{1, 2}}.Validate()).To(BeTrue())
+		Expect(types.VersionsRequest{Versions: []int{1}}.Validate()).To(BeFalse())
+		Expect(types.VersionsRequest{Name: "/some/password"}.Validate()).To(BeFalse())
+	})
+
+	It("rejects version numbers that can't exist", func() {
+		Expect(types.VersionsRequest{Name: "/some/password", Versions: []int{0}}.Validate()).To(BeFalse())
+		Expect(types.VersionsRequest{Name: "/some/password", Versions: []int{-1}}.Validate()).To(BeFalse())
+	})
+})
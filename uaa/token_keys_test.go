@@ -0,0 +1,187 @@
+package uaa_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/cloudfoundry-community/bosh-vault/auth"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/uaa"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Keys", func() {
+	var fakeUaa *httptest.Server
+	var api *echo.Echo
+	var keysMutex sync.Mutex
+	var servedKeys []map[string]string
+	var keyRequests int
+	var previousRefreshInterval time.Duration
+
+	newKey := func() *rsa.PrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		return key
+	}
+
+	pemKey := func(kid string, key *rsa.PrivateKey) map[string]string {
+		publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		Expect(err).ToNot(HaveOccurred())
+		return map[string]string{
+			"kid":   kid,
+			"alg":   "RS256",
+			"kty":   "RSA",
+			"value": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		}
+	}
+
+	serveKeys := func(keys ...map[string]string) {
+		keysMutex.Lock()
+		defer keysMutex.Unlock()
+		servedKeys = keys
+	}
+
+	requests := func() int {
+		keysMutex.Lock()
+		defer keysMutex.Unlock()
+		return keyRequests
+	}
+
+	// the server fetches the keys when it starts
+	startApi := func() {
+		bvConfig := config.ParseConfig(nil)
+		bvConfig.Uaa.Address = fakeUaa.URL
+		uaaClient := uaa.GetUaa(bvConfig)
+		Expect(uaaClient.Keys.Refresh()).To(Succeed())
+		api = echo.New()
+		api.Use(auth.Middleware(nil, uaaClient))
+		api.GET("/v1/data", func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		})
+	}
+
+	requestWithAudience := func(aud interface{}, kid string, key *rsa.PrivateKey) int {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"aud": aud,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/data", nil)
+		req.Header.Set(echo.HeaderAuthorization, "bearer "+signed)
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	request := func(kid string, key *rsa.PrivateKey) int {
+		return requestWithAudience([]interface{}{"config_server"}, kid, key)
+	}
+
+	BeforeEach(func() {
+		previousRefreshInterval = auth.UnknownKeyRefreshInterval
+		keyRequests = 0
+		fakeUaa = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/token_keys"))
+			keysMutex.Lock()
+			defer keysMutex.Unlock()
+			keyRequests++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": servedKeys})
+		}))
+	})
+
+	AfterEach(func() {
+		auth.UnknownKeyRefreshInterval = previousRefreshInterval
+		fakeUaa.Close()
+	})
+
+	Context("with several keys", func() {
+		var oldKey, newerKey *rsa.PrivateKey
+
+		BeforeEach(func() {
+			oldKey = newKey()
+			newerKey = newKey()
+			serveKeys(pemKey("old", oldKey), pemKey("new", newerKey))
+			startApi()
+		})
+
+		It("checks tokens with the key named by their kid", func() {
+			Expect(request("old", oldKey)).To(Equal(http.StatusOK))
+			Expect(request("new", newerKey)).To(Equal(http.StatusOK))
+			Expect(request("new", oldKey)).To(Equal(http.StatusUnauthorized))
+			// without a kid it isn't known which key to use
+			Expect(request("", oldKey)).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	It("picks up rotated keys", func() {
+		auth.UnknownKeyRefreshInterval = 0
+		firstKey := newKey()
+		rotatedKey := newKey()
+		serveKeys(pemKey("first", firstKey))
+
+		startApi()
+		Expect(request("first", firstKey)).To(Equal(http.StatusOK))
+
+		serveKeys(pemKey("first", firstKey), pemKey("rotated", rotatedKey))
+		Expect(request("rotated", rotatedKey)).To(Equal(http.StatusOK))
+		Expect(request("first", firstKey)).To(Equal(http.StatusOK))
+	})
+
+	It("rate limits fetching the keys for unknown kids", func() {
+		auth.UnknownKeyRefreshInterval = time.Hour
+		knownKey := newKey()
+		serveKeys(pemKey("known", knownKey))
+
+		startApi()
+		fetchedOnStartup := requests()
+
+		unknownKey := newKey()
+		for i := 0; i < 5; i++ {
+			Expect(request("unknown", unknownKey)).To(Equal(http.StatusUnauthorized))
+		}
+		Expect(requests()).To(Equal(fetchedOnStartup))
+	})
+
+	It("reads keys listed by modulus and exponent", func() {
+		key := newKey()
+		serveKeys(map[string]string{
+			"kid": "jwk",
+			"alg": "RS256",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		})
+
+		startApi()
+		Expect(request("jwk", key)).To(Equal(http.StatusOK))
+		// a single key is used for tokens without a kid
+		Expect(request("", key)).To(Equal(http.StatusOK))
+	})
+	It("checks the audience whether it's a list or a string", func() {
+		key := newKey()
+		serveKeys(pemKey("key", key))
+
+		startApi()
+		Expect(requestWithAudience("config_server", "key", key)).To(Equal(http.StatusOK))
+		Expect(requestWithAudience([]interface{}{"openid", "config_server"}, "key", key)).To(Equal(http.StatusOK))
+		Expect(requestWithAudience("cloud_controller", "key", key)).To(Equal(http.StatusUnauthorized))
+		Expect(requestWithAudience(nil, "key", key)).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/hashicorp/vault/api"
+	"io/ioutil"
+	"strings"
+)
+
+const DefaultAppRoleMount = "approle"
+const DefaultCertMount = "cert"
+const DefaultKubernetesMount = "kubernetes"
+const DefaultKubernetesJwtFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Authenticators log in to Vault to get a token, bosh-vault logs in again whenever its token can't be renewed any longer
+type Authenticator interface {
+	Login(client *api.Client) (*api.SecretAuth, error)
+}
+
+// Returns nil when the static token from the configuration should be used
+func GetAuthenticator(authConfig config.VaultAuthConfiguration) (Authenticator, error) {
+	switch authConfig.Method {
+	case "", config.VaultAuthMethodToken:
+		return nil, nil
+	case config.VaultAuthMethodAppRole:
+		if authConfig.RoleId == "" || authConfig.SecretIdFile == "" {
+			return nil, errors.New("approle auth requires a roleid and a secretidfile")
+		}
+		return &AppRoleAuthenticator{
+			Mount:        mountOrDefault(authConfig.Mount, DefaultAppRoleMount),
+			RoleId:       authConfig.RoleId,
+			SecretIdFile: authConfig.SecretIdFile,
+		}, nil
+	case config.VaultAuthMethodCert:
+		if authConfig.CertFile == "" || authConfig.KeyFile == "" {
+			return nil, errors.New("cert auth requires a certfile and a keyfile")
+		}
+		return &CertAuthenticator{
+			Mount:    mountOrDefault(authConfig.Mount, DefaultCertMount),
+			Role:     authConfig.Role,
+			CertFile: authConfig.CertFile,
+			KeyFile:  authConfig.KeyFile,
+		}, nil
+	case config.VaultAuthMethodKubernetes:
+		if authConfig.Role == "" {
+			return nil, errors.New("kubernetes auth requires a role")
+		}
+		jwtFile := authConfig.JwtFile
+		if jwtFile == "" {
+			jwtFile = DefaultKubernetesJwtFile
+		}
+		return &KubernetesAuthenticator{
+			Mount:   mountOrDefault(authConfig.Mount, DefaultKubernetesMount),
+			Role:    authConfig.Role,
+			JwtFile: jwtFile,
+		}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown vault auth method %s, must be one of: %s, %s, %s, %s", authConfig.Method, config.VaultAuthMethodToken, config.VaultAuthMethodAppRole, config.VaultAuthMethodCert, config.VaultAuthMethodKubernetes))
+	}
+}
+
+// for logging, token files aren't an auth method in the configuration but are handled as one
+func authMethodName(vaultConfig config.VaultConfiguration) string {
+	if vaultConfig.TokenFile != "" {
+		return "tokenfile"
+	}
+	return vaultConfig.Auth.Method
+}
+
+func mountOrDefault(mount string, defaultMount string) string {
+	if mount == "" {
+		return defaultMount
+	}
+	return mount
+}
+
+type AppRoleAuthenticator struct {
+	Mount        string
+	RoleId       string
+	SecretIdFile string
+}
+
+// The secret id is read on every login so it can be rotated on disk without restarting bosh-vault
+func (a *AppRoleAuthenticator) Login(client *api.Client) (*api.SecretAuth, error) {
+	secretId, err := ioutil.ReadFile(a.SecretIdFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("problem reading approle secret id from %s: %s", a.SecretIdFile, err))
+	}
+
+	return login(client, fmt.Sprintf("auth/%s/login", a.Mount), map[string]interface{}{
+		"role_id":   a.RoleId,
+		"secret_id": strings.TrimSpace(string(secretId)),
+	})
+}
+
+// The client certificate is presented on every TLS connection to Vault, not only the login. It's read from disk on every
+// handshake so a rotated certificate is picked up without restarting bosh-vault.
+type CertAuthenticator struct {
+	Mount    string
+	Role     string
+	CertFile string
+	KeyFile  string
+}
+
+func (c *CertAuthenticator) Login(client *api.Client) (*api.SecretAuth, error) {
+	data := map[string]interface{}{}
+	// without a role Vault tries every role configured on the mount
+	if c.Role != "" {
+		data["name"] = c.Role
+	}
+	return login(client, fmt.Sprintf("auth/%s/login", c.Mount), data)
+}
+
+func (c *CertAuthenticator) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("problem loading vault client certificate %s: %s", c.CertFile, err))
+	}
+	return &certificate, nil
+}
+
+// Service account tokens are rotated by the kubelet so the token is read on every login
+type KubernetesAuthenticator struct {
+	Mount   string
+	Role    string
+	JwtFile string
+}
+
+func (k *KubernetesAuthenticator) Login(client *api.Client) (*api.SecretAuth, error) {
+	jwt, err := ioutil.ReadFile(k.JwtFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("problem reading service account token from %s: %s", k.JwtFile, err))
+	}
+
+	return login(client, fmt.Sprintf("auth/%s/login", k.Mount), map[string]interface{}{
+		"role": k.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+// Logs in with a copy of the client, the client's own token may be expired which some Vaults reject even on login
+func login(client *api.Client, path string, data map[string]interface{}) (*api.SecretAuth, error) {
+	loginClient, err := client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	loginClient.ClearToken()
+	// clones don't keep headers, logins need the namespace header
+	loginClient.SetHeaders(client.Headers())
+
+	loginResponse, err := loginClient.Logical().Write(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if loginResponse == nil || loginResponse.Auth == nil || loginResponse.Auth.ClientToken == "" {
+		return nil, errors.New(fmt.Sprintf("no token returned by %s", path))
+	}
+	return loginResponse.Auth, nil
+}
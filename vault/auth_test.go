@@ -0,0 +1,153 @@
+package vault_test
+
+import (
+	"encoding/json"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"github.com/hashicorp/vault/api"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault Auth", func() {
+	It("uses the static token when no auth method is configured", func() {
+		authenticator, err := vault.GetAuthenticator(config.VaultAuthConfiguration{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authenticator).To(BeNil())
+	})
+
+	It("rejects unknown auth methods and incomplete approle configuration", func() {
+		_, err := vault.GetAuthenticator(config.VaultAuthConfiguration{Method: "carrier-pigeon"})
+		Expect(err).To(HaveOccurred())
+		_, err = vault.GetAuthenticator(config.VaultAuthConfiguration{Method: config.VaultAuthMethodAppRole, RoleId: "role"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("requires the files and roles each auth method logs in with", func() {
+		_, err := vault.GetAuthenticator(config.VaultAuthConfiguration{Method: config.VaultAuthMethodCert, CertFile: "cert.pem"})
+		Expect(err).To(HaveOccurred())
+		_, err = vault.GetAuthenticator(config.VaultAuthConfiguration{Method: config.VaultAuthMethodKubernetes})
+		Expect(err).To(HaveOccurred())
+
+		authenticator, err := vault.GetAuthenticator(config.VaultAuthConfiguration{Method: config.VaultAuthMethodKubernetes, Role: "bosh-vault"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authenticator).To(Equal(&vault.KubernetesAuthenticator{
+			Mount:   vault.DefaultKubernetesMount,
+			Role:    "bosh-vault",
+			JwtFile: vault.DefaultKubernetesJwtFile,
+		}))
+	})
+
+	Context("kubernetes", func() {
+		var loginRequest map[string]interface{}
+		var loginPath string
+		var fakeVault *httptest.Server
+		var jwtFile string
+
+		BeforeEach(func() {
+			fakeVault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				loginPath = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&loginRequest)
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "kubernetes-token", "lease_duration": 1200, "renewable": true}}`))
+			}))
+
+			file, err := ioutil.TempFile("", "service-account-token")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = file.WriteString("service.account.jwt\n")
+			Expect(err).ToNot(HaveOccurred())
+			jwtFile = file.Name()
+		})
+
+		AfterEach(func() {
+			fakeVault.Close()
+			_ = os.Remove(jwtFile)
+		})
+
+		It("logs in with the role and the service account token", func() {
+			client, err := api.NewClient(&api.Config{Address: fakeVault.URL})
+			Expect(err).ToNot(HaveOccurred())
+
+			authenticator := vault.KubernetesAuthenticator{
+				Mount:   "k8s",
+				Role:    "bosh-vault",
+				JwtFile: jwtFile,
+			}
+			auth, err := authenticator.Login(client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(auth.ClientToken).To(Equal("kubernetes-token"))
+			Expect(loginPath).To(Equal("/v1/auth/k8s/login"))
+			Expect(loginRequest).To(Equal(map[string]interface{}{
+				"role": "bosh-vault",
+				"jwt":  "service.account.jwt",
+			}))
+		})
+	})
+
+	Context("approle", func() {
+		var secretIdFile *os.File
+		var roleId string
+
+		BeforeEach(func() {
+			_ = healthyVault.Client.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{Type: "approle"})
+			_, err := healthyVault.Client.Logical().Write("auth/approle/role/bosh-vault", map[string]interface{}{
+				"token_ttl":     "20m",
+				"token_max_ttl": "1h",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			roleIdResponse, err := healthyVault.Client.Logical().Read("auth/approle/role/bosh-vault/role-id")
+			Expect(err).ToNot(HaveOccurred())
+			roleId = roleIdResponse.Data["role_id"].(string)
+
+			secretIdResponse, err := healthyVault.Client.Logical().Write("auth/approle/role/bosh-vault/secret-id", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			secretIdFile, err = ioutil.TempFile("", "secret-id")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = secretIdFile.WriteString(secretIdResponse.Data["secret_id"].(string) + "\n")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.Remove(secretIdFile.Name())
+		})
+
+		It("logs in with the role id and the secret id from a file", func() {
+			approleVault, err := vault.GetVault(config.VaultConfiguration{
+				Address: healthyVaultAddress,
+				Mount:   "config-server",
+				Auth: config.VaultAuthConfiguration{
+					Method:       config.VaultAuthMethodAppRole,
+					RoleId:       roleId,
+					SecretIdFile: secretIdFile.Name(),
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(approleVault.Client.Token()).ToNot(BeEmpty())
+			Expect(approleVault.Client.Token()).ToNot(Equal(healthyVault.Client.Token()))
+
+			tokenInfo, err := approleVault.Client.Auth().Token().LookupSelf()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenInfo.Data["path"]).To(Equal("auth/approle/login"))
+		})
+
+		It("fails when the secret id is wrong", func() {
+			Expect(ioutil.WriteFile(secretIdFile.Name(), []byte("not-the-secret-id"), 0600)).To(Succeed())
+			_, err := vault.GetVault(config.VaultConfiguration{
+				Address: healthyVaultAddress,
+				Mount:   "config-server",
+				Auth: config.VaultAuthConfiguration{
+					Method:       config.VaultAuthMethodAppRole,
+					RoleId:       roleId,
+					SecretIdFile: secretIdFile.Name(),
+				},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
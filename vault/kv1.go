@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The V1 methods read and write a KV1 mount, names map directly to paths under the mount
+
+func (v *Vault) GetV1(name string) (map[string]interface{}, error) {
+	response, err := v.Client.Logical().Read(v.parseV1Path(name))
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, errors.New("secret not found")
+	}
+	return response.Data, nil
+}
+
+func (v *Vault) SetV1(name string, value map[string]interface{}) error {
+	_, err := v.Client.Logical().Write(v.parseV1Path(name), value)
+	return err
+}
+
+func (v *Vault) DeleteV1(name string) error {
+	_, err := v.Client.Logical().Delete(v.parseV1Path(name))
+	return err
+}
+
+// Lists the keys directly under the given path, keys ending in a / are "folders" that contain more keys
+func (v *Vault) ListV1(name string) ([]string, error) {
+	listResponse, err := v.Client.Logical().List(v.parseV1Path(name))
+	if err != nil {
+		return nil, err
+	}
+	return listKeys(listResponse), nil
+}
+
+func (v *Vault) parseV1Path(name string) string {
+	return fmt.Sprintf("%s%s", v.Config.Mount, v.sanitizeName(name))
+}
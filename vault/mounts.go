@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/hashicorp/vault/api"
+	"net/http"
+	"strings"
+)
+
+const KVVersion1 = 1
+const KVVersion2 = 2
+
+type Mount struct {
+	Path string
+	Type string
+	// 1 or 2 for KV mounts, 0 for every other secret engine
+	KVVersion int
+}
+
+// Finds the mount a path belongs to, a nil mount means nothing is mounted there. Reading sys/mounts needs a privileged
+// token so the endpoint the vault cli uses for the same purpose, which only needs access to the path itself, is tried
+// when it is denied. A parent namespace doesn't list the mounts of its children, in a child namespace (see InNamespace)
+// the child's own sys endpoints are asked.
+func (v *Vault) GetMount(path string) (*Mount, error) {
+	path = strings.TrimPrefix(strings.Trim(path, "/")+"/", v.childNamespace)
+
+	mountsResponse, err := v.Client.Logical().Read(v.childNamespace + "sys/mounts")
+	if err == nil {
+		if mountsResponse == nil || mountsResponse.Data == nil {
+			return nil, errors.New("data from server response is empty")
+		}
+		var mount *Mount
+		servingPath := ""
+		for mountPath, mountOutput := range mountsResponse.Data {
+			// mounts can be nested, the longest match is the one serving the path
+			if !strings.HasPrefix(path, mountPath) || len(mountPath) <= len(servingPath) {
+				continue
+			}
+			servingPath = mountPath
+			mountData, _ := mountOutput.(map[string]interface{})
+			mount = v.parseMount(mountPath, mountData)
+		}
+		return mount, nil
+	}
+
+	if !IsPermissionDenied(err) {
+		return nil, err
+	}
+	logger.Log.Debugf("could not read %ssys/mounts on %s, asking for the mount of %s directly: %s", v.childNamespace, v.Config.Address, path, err)
+	mountResponse, err := v.Client.Logical().Read(v.childNamespace + "sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if mountResponse == nil {
+		return nil, nil
+	}
+
+	mountPath, _ := mountResponse.Data["path"].(string)
+	return v.parseMount(mountPath, mountResponse.Data), nil
+}
+
+// Mount paths are reported relative to the namespace that was asked, they are returned the way the Vault's own paths
+// are written
+func (v *Vault) parseMount(mountPath string, mountData map[string]interface{}) *Mount {
+	mount := &Mount{Path: v.childNamespace + mountPath}
+	mount.Type, _ = mountData["type"].(string)
+	var version string
+	if options, ok := mountData["options"].(map[string]interface{}); ok {
+		version, _ = options["version"].(string)
+	}
+	mount.KVVersion = kvVersion(mount.Type, version)
+	return mount
+}
+
+// Whether Vault answered with a 403, any other error means it couldn't answer the request at all. This version of the
+// api client only puts the status code into the error text.
+func IsPermissionDenied(err error) bool {
+	return err != nil && strings.Contains(err.Error(), fmt.Sprintf("Code: %d.", http.StatusForbidden))
+}
+
+// The KV version of the Vault's own mount
+func (v *Vault) KVVersion() (int, error) {
+	mount, err := v.GetMount(v.Config.Mount)
+	if err != nil {
+		return 0, err
+	}
+	if mount == nil {
+		return 0, errors.New(fmt.Sprintf("no mount found at %s", v.Config.Mount))
+	}
+	if mount.KVVersion == 0 {
+		return 0, errors.New(fmt.Sprintf("%s is a %s mount, not a kv mount", mount.Path, mount.Type))
+	}
+	return mount.KVVersion, nil
+}
+
+func (v *Vault) CreateKV2Mount() error {
+	return v.Client.Sys().Mount(v.Config.Mount, &api.MountInput{
+		Type:        "kv",
+		Description: "bosh-vault config server credentials",
+		Options: map[string]string{
+			"version": "2",
+		},
+	})
+}
+
+func kvVersion(mountType string, version string) int {
+	switch mountType {
+	case "kv":
+		if version == "2" {
+			return KVVersion2
+		}
+		return KVVersion1
+	case "generic":
+		// KV was called generic before Vault 0.8
+		return KVVersion1
+	default:
+		return 0
+	}
+}
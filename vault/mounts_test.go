@@ -0,0 +1,79 @@
+package vault_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"github.com/hashicorp/vault/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mounts", func() {
+	It("finds the mount serving a path", func() {
+		mount, err := healthyVault.GetMount("config-server/some/credential")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mount).ToNot(BeNil())
+		Expect(mount.Path).To(Equal("config-server/"))
+		Expect(mount.Type).To(Equal("kv"))
+		Expect(mount.KVVersion).To(Equal(vault.KVVersion2))
+
+		mount, err = healthyVault.GetMount("cubbyhole/something")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mount.KVVersion).To(Equal(0))
+	})
+
+	It("returns no mount when nothing is mounted at the path", func() {
+		mount, err := healthyVault.GetMount("not-mounted/anywhere")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mount).To(BeNil())
+
+		missingMountVault := healthyVault
+		missingMountVault.Config.Mount = "not-mounted"
+		_, err = missingMountVault.KVVersion()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("can create a kv2 mount", func() {
+		createdMountVault := healthyVault
+		createdMountVault.Config.Mount = "created-by-bosh-vault"
+		Expect(createdMountVault.CreateKV2Mount()).To(Succeed())
+
+		kvVersion, err := createdMountVault.KVVersion()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kvVersion).To(Equal(vault.KVVersion2))
+	})
+
+	It("finds the mount without access to sys/mounts", func() {
+		err := healthyVault.Client.Sys().PutPolicy("config-server-only", `path "config-server/*" { capabilities = ["read", "list"] }`)
+		Expect(err).ToNot(HaveOccurred())
+		tokenResponse, err := healthyVault.Client.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{"config-server-only"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		client, err := healthyVault.Client.Clone()
+		Expect(err).ToNot(HaveOccurred())
+		client.SetToken(tokenResponse.Auth.ClientToken)
+		unprivilegedVault := healthyVault
+		unprivilegedVault.Client = client
+
+		_, err = client.Sys().ListMounts()
+		Expect(vault.IsPermissionDenied(err)).To(BeTrue())
+
+		kvVersion, err := unprivilegedVault.KVVersion()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kvVersion).To(Equal(vault.KVVersion2))
+	})
+
+	It("returns the error when Vault can't be reached", func() {
+		client, err := healthyVault.Client.Clone()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.SetAddress("http://127.0.0.1:1")).To(Succeed())
+		unreachableVault := healthyVault
+		unreachableVault.Client = client
+
+		_, err = unreachableVault.GetMount("config-server/some/credential")
+		Expect(err).To(HaveOccurred())
+		Expect(vault.IsPermissionDenied(err)).To(BeFalse())
+	})
+})
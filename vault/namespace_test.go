@@ -0,0 +1,93 @@
+package vault_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault Namespaces", func() {
+	var requestedPaths []string
+	var requestedNamespaces []string
+	var fakeVault *httptest.Server
+
+	BeforeEach(func() {
+		requestedPaths = make([]string, 0)
+		requestedNamespaces = make([]string, 0)
+		fakeVault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestedPaths = append(requestedPaths, r.URL.Path)
+			requestedNamespaces = append(requestedNamespaces, r.Header.Get("X-Vault-Namespace"))
+			_, _ = w.Write([]byte(`{"data": {"data": {"value": "namespaced"}}}`))
+		}))
+	})
+
+	AfterEach(func() {
+		fakeVault.Close()
+	})
+
+	It("sends the namespace with every request", func() {
+		namespacedVault, err := vault.GetVault(config.VaultConfiguration{
+			Address:   fakeVault.URL,
+			Token:     "token",
+			Mount:     "config-server",
+			Namespace: "team-a",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(namespacedVault.Exists("/some/password")).To(BeTrue())
+		_, err = namespacedVault.Get("/some/password", map[string]string{})
+		Expect(err).ToNot(HaveOccurred())
+		namespacedVault.Healthy()
+
+		Expect(requestedPaths).To(ContainElement("/v1/config-server/data/some/password"))
+		Expect(requestedPaths).To(ContainElement("/v1/sys/health"))
+		for _, namespace := range requestedNamespaces {
+			Expect(namespace).To(Equal("team-a"))
+		}
+	})
+
+	It("targets child namespaces through the request path", func() {
+		namespacedVault, err := vault.GetVault(config.VaultConfiguration{
+			Address:   fakeVault.URL,
+			Token:     "token",
+			Mount:     "config-server",
+			Namespace: "team-a",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		childVault := namespacedVault.InNamespace("/shared/")
+		Expect(childVault.Exists("/some/password")).To(BeTrue())
+		Expect(requestedPaths).To(ContainElement("/v1/shared/config-server/data/some/password"))
+		Expect(namespacedVault.Config.Mount).To(Equal("config-server"))
+	})
+	It("looks up mounts of child namespaces in the child namespace", func() {
+		mountsVault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestedPaths = append(requestedPaths, r.URL.Path)
+			if r.URL.Path != "/v1/shared/sys/mounts" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data": {"config-server/": {"type": "kv", "options": {"version": "2"}}}}`))
+		}))
+		defer mountsVault.Close()
+		namespacedVault, err := vault.GetVault(config.VaultConfiguration{
+			Address:   mountsVault.URL,
+			Token:     "token",
+			Mount:     "config-server",
+			Namespace: "team-a",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		childVault := namespacedVault.InNamespace("shared")
+		mount, err := childVault.GetMount(childVault.Config.Mount)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mount).ToNot(BeNil())
+		Expect(mount.Path).To(Equal("shared/config-server/"))
+		Expect(mount.KVVersion).To(Equal(vault.KVVersion2))
+		Expect(requestedPaths).To(ContainElement("/v1/shared/sys/mounts"))
+	})
+})
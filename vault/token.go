@@ -0,0 +1,300 @@
+package vault
+
+import (
+	"errors"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/hashicorp/vault/api"
+	"sync"
+	"time"
+)
+
+const TokenStateActive = "active"                // the token is valid and being renewed, or never expires
+const TokenStateRenewalFailed = "renewal-failed" // renewing or logging in failed, the token is used until it expires
+const TokenStateExpired = "expired"              // the token's ttl ran out, requests to Vault will be denied
+const TokenStateStopped = "stopped"              // the manager was stopped, the token is no longer renewed
+const TokenStateUnknown = "unknown"              // the token hasn't been looked up yet
+
+type TokenStatus struct {
+	State     string     `json:"state"`
+	Renewable bool       `json:"renewable"`
+	Ttl       int        `json:"ttl"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Keeps a Vault client's token alive with the Vault api's Renewer. When renewing stops, because it failed or the token
+// reached its max ttl, tokens from an auth method are replaced by logging in again and static tokens are looked up
+// again so renewal resumes if Vault was only unreachable for a while.
+type TokenManager struct {
+	client        *api.Client
+	config        config.VaultConfiguration
+	authenticator Authenticator
+
+	mutex     sync.RWMutex
+	state     string
+	renewable bool
+	expiresAt time.Time
+	lastError error
+
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+	refreshCh chan struct{}
+	waitGroup sync.WaitGroup
+}
+
+func NewTokenManager(client *api.Client, vaultConfig config.VaultConfiguration, authenticator Authenticator) *TokenManager {
+	return &TokenManager{
+		client:        client,
+		config:        vaultConfig,
+		authenticator: authenticator,
+		state:         TokenStateUnknown,
+		stopCh:        make(chan struct{}),
+		refreshCh:     make(chan struct{}, 1),
+	}
+}
+
+// Starts managing the token, auth may be nil in which case the token is looked up (or logged in for) first
+func (m *TokenManager) Start(auth *api.SecretAuth) {
+	m.waitGroup.Add(1)
+	go m.run(auth)
+
+	if tokenFileAuthenticator, ok := m.authenticator.(*TokenFileAuthenticator); ok {
+		m.waitGroup.Add(1)
+		go func() {
+			defer m.waitGroup.Done()
+			tokenFileAuthenticator.watch(m)
+		}()
+	}
+}
+
+// Replaces the current token by logging in again as soon as possible
+func (m *TokenManager) Refresh() {
+	select {
+	case m.refreshCh <- struct{}{}:
+	default:
+		// a refresh is already pending
+	}
+}
+
+// Stops renewing and waits for the renewal goroutines to exit, safe to call more than once
+func (m *TokenManager) Stop() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+	m.waitGroup.Wait()
+}
+
+func (m *TokenManager) Status() TokenStatus {
+	if m == nil {
+		return TokenStatus{State: TokenStateUnknown}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status := TokenStatus{
+		State:     m.state,
+		Renewable: m.renewable,
+	}
+	if m.lastError != nil {
+		status.LastError = m.lastError.Error()
+	}
+	if !m.expiresAt.IsZero() {
+		expiresAt := m.expiresAt
+		status.ExpiresAt = &expiresAt
+		status.Ttl = int(time.Until(expiresAt).Seconds())
+		if status.Ttl <= 0 {
+			status.Ttl = 0
+			status.State = TokenStateExpired
+		}
+	}
+	return status
+}
+
+// An expired token is the only state in which requests to Vault are known to fail
+func (m *TokenManager) Usable() bool {
+	return m.Status().State != TokenStateExpired
+}
+
+func (m *TokenManager) run(auth *api.SecretAuth) {
+	defer m.waitGroup.Done()
+	defer m.setState(TokenStateStopped)
+
+	renewalInterval := time.Duration(m.config.RenewalInterval) * time.Second
+
+	for {
+		if auth == nil {
+			var err error
+			auth, err = m.acquire()
+			if err != nil {
+				m.failed(err)
+				if !m.wait(loginRetryInterval) {
+					return
+				}
+				continue
+			}
+		}
+		m.update(auth)
+
+		// tokens without a ttl (like root tokens) never need renewing
+		if auth.LeaseDuration == 0 {
+			if !m.wait(0) {
+				return
+			}
+			auth = nil
+			continue
+		}
+
+		if !auth.Renewable {
+			if m.authenticator == nil {
+				// nothing can be done for a static token that can't be renewed, it is reported as expired once it is
+				if !m.wait(0) {
+					return
+				}
+				auth = nil
+				continue
+			}
+			// log in again once most of the ttl has passed
+			if !m.wait(time.Duration(auth.LeaseDuration) * time.Second * 2 / 3) {
+				return
+			}
+			auth = nil
+			continue
+		}
+
+		renewer, err := m.client.NewRenewer(&api.RenewerInput{
+			Secret:    &api.Secret{Auth: auth},
+			Increment: m.config.RenewalInterval,
+		})
+		if err != nil {
+			m.failed(err)
+			if !m.wait(loginRetryInterval) {
+				return
+			}
+			auth = nil
+			continue
+		}
+
+		go renewer.Renew()
+		stopped, refreshed, err := m.watch(renewer)
+		if stopped {
+			return
+		}
+
+		if refreshed {
+			auth = nil
+			continue
+		}
+
+		if err != nil {
+			m.failed(err)
+			if !m.wait(loginRetryInterval) {
+				return
+			}
+		} else if m.authenticator == nil {
+			// a static token reached its max ttl, keep checking in case it is extended out of band
+			if !m.wait(renewalInterval) {
+				return
+			}
+		}
+		auth = nil
+	}
+}
+
+// Relays renewals until the renewer is done or the manager is stopped or refreshed
+func (m *TokenManager) watch(renewer *api.Renewer) (stopped bool, refreshed bool, err error) {
+	for {
+		select {
+		case <-m.stopCh:
+			renewer.Stop()
+			return true, false, nil
+		case <-m.refreshCh:
+			renewer.Stop()
+			return false, true, nil
+		case renewal := <-renewer.RenewCh():
+			if renewal != nil && renewal.Secret != nil && renewal.Secret.Auth != nil {
+				m.update(renewal.Secret.Auth)
+			}
+		case err := <-renewer.DoneCh():
+			return false, false, err
+		}
+	}
+}
+
+func (m *TokenManager) acquire() (*api.SecretAuth, error) {
+	if m.authenticator != nil {
+		auth, err := m.authenticator.Login(m.client)
+		if err != nil {
+			return nil, err
+		}
+		logger.Log.Infof("Logged in to %s with %s auth", m.config.Address, authMethodName(m.config))
+		m.client.SetToken(auth.ClientToken)
+		return auth, nil
+	}
+
+	lookup, err := m.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+	if lookup == nil {
+		return nil, errors.New("no token information returned by Vault")
+	}
+	ttl, _ := lookup.TokenTTL()
+	renewable, _ := lookup.TokenIsRenewable()
+	return &api.SecretAuth{
+		ClientToken:   m.client.Token(),
+		LeaseDuration: int(ttl.Seconds()),
+		Renewable:     renewable,
+	}, nil
+}
+
+func (m *TokenManager) update(auth *api.SecretAuth) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.state = TokenStateActive
+	m.renewable = auth.Renewable
+	m.lastError = nil
+	m.expiresAt = time.Time{}
+	if auth.LeaseDuration > 0 {
+		m.expiresAt = time.Now().Add(time.Duration(auth.LeaseDuration) * time.Second)
+	}
+}
+
+func (m *TokenManager) failed(err error) {
+	logger.Log.Errorf("Problem keeping the token for %s alive, will try again: %s", m.config.Address, err)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = TokenStateRenewalFailed
+	m.lastError = err
+}
+
+func (m *TokenManager) setState(state string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = state
+}
+
+// Waits for the duration (or until refreshed, a duration of 0 waits for a refresh only), returns false if the manager
+// was stopped while waiting
+func (m *TokenManager) wait(duration time.Duration) bool {
+	var timeout <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-m.stopCh:
+		return false
+	case <-m.refreshCh:
+		return true
+	case <-timeout:
+		return true
+	}
+}
@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// How often token files are checked for a new token, Vault Agent rewrites its sink whenever it gets a new token
+var TokenFilePollInterval = 5 * time.Second
+
+// Uses whatever token is in the file, typically a Vault Agent sink. "Logging in" reads the file again so the token
+// manager picks up a new token whenever the current one can't be renewed or the file changes.
+type TokenFileAuthenticator struct {
+	Path string
+}
+
+func (t *TokenFileAuthenticator) Login(client *api.Client) (*api.SecretAuth, error) {
+	token, err := t.readToken()
+	if err != nil {
+		return nil, err
+	}
+
+	lookupClient, err := client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	lookupClient.SetHeaders(client.Headers())
+	lookupClient.SetToken(token)
+
+	lookup, err := lookupClient.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("problem looking up the token from %s: %s", t.Path, err))
+	}
+	if lookup == nil {
+		return nil, errors.New(fmt.Sprintf("no token information returned by Vault for the token from %s", t.Path))
+	}
+	ttl, _ := lookup.TokenTTL()
+	renewable, _ := lookup.TokenIsRenewable()
+	return &api.SecretAuth{
+		ClientToken:   token,
+		LeaseDuration: int(ttl.Seconds()),
+		Renewable:     renewable,
+	}, nil
+}
+
+func (t *TokenFileAuthenticator) readToken() (string, error) {
+	contents, err := ioutil.ReadFile(t.Path)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("problem reading vault token from %s: %s", t.Path, err))
+	}
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return "", errors.New(fmt.Sprintf("vault token file %s is empty", t.Path))
+	}
+	return token, nil
+}
+
+// Asks the token manager to swap in the token from the file whenever it differs from the one in use
+func (t *TokenFileAuthenticator) watch(m *TokenManager) {
+	ticker := time.NewTicker(TokenFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			token, err := t.readToken()
+			// a missing or empty file is usually the agent in the middle of rewriting it, keep the current token
+			if err == nil && token != m.client.Token() {
+				m.Refresh()
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package vault_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Files", func() {
+	var fakeVault *httptest.Server
+	var tokenFile string
+	var originalPollInterval time.Duration
+
+	BeforeEach(func() {
+		originalPollInterval = vault.TokenFilePollInterval
+		vault.TokenFilePollInterval = 10 * time.Millisecond
+
+		fakeVault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"data": {"ttl": 3600, "renewable": false}}`))
+		}))
+
+		file, err := ioutil.TempFile("", "vault-token")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = file.WriteString("first-token\n")
+		Expect(err).ToNot(HaveOccurred())
+		tokenFile = file.Name()
+	})
+
+	AfterEach(func() {
+		vault.TokenFilePollInterval = originalPollInterval
+		fakeVault.Close()
+		_ = os.Remove(tokenFile)
+	})
+
+	It("uses the token from the file and swaps in new tokens written to it", func() {
+		tokenFileVault, err := vault.GetVault(config.VaultConfiguration{
+			Address:   fakeVault.URL,
+			TokenFile: tokenFile,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer tokenFileVault.Close()
+		Expect(tokenFileVault.Client.Token()).To(Equal("first-token"))
+
+		Expect(ioutil.WriteFile(tokenFile, []byte("second-token"), 0600)).To(Succeed())
+		Eventually(tokenFileVault.Client.Token).Should(Equal("second-token"))
+		Expect(tokenFileVault.TokenManager.Status().State).To(Equal(vault.TokenStateActive))
+	})
+
+	It("keeps the current token while the file is empty", func() {
+		tokenFileVault, err := vault.GetVault(config.VaultConfiguration{
+			Address:   fakeVault.URL,
+			TokenFile: tokenFile,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer tokenFileVault.Close()
+
+		Expect(ioutil.WriteFile(tokenFile, []byte(""), 0600)).To(Succeed())
+		Consistently(tokenFileVault.Client.Token, 100*time.Millisecond).Should(Equal("first-token"))
+	})
+
+	It("can't be combined with an auth method", func() {
+		_, err := vault.GetVault(config.VaultConfiguration{
+			Address:   fakeVault.URL,
+			TokenFile: tokenFile,
+			Auth: config.VaultAuthConfiguration{
+				Method: config.VaultAuthMethodKubernetes,
+				Role:   "bosh-vault",
+			},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
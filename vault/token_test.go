@@ -0,0 +1,105 @@
+package vault_test
+
+import (
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"github.com/cloudfoundry-community/bosh-vault/vault"
+	"github.com/hashicorp/vault/api"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type countingAuthenticator struct {
+	logins int32
+	auth   api.SecretAuth
+}
+
+func (c *countingAuthenticator) Login(client *api.Client) (*api.SecretAuth, error) {
+	atomic.AddInt32(&c.logins, 1)
+	auth := c.auth
+	return &auth, nil
+}
+
+var _ = Describe("Token Manager", func() {
+	var fakeVault *httptest.Server
+	var client *api.Client
+	var lookupResponse string
+	var renewResponse string
+
+	BeforeEach(func() {
+		fakeVault = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/auth/token/lookup-self":
+				_, _ = w.Write([]byte(lookupResponse))
+			case "/v1/auth/token/renew-self":
+				_, _ = w.Write([]byte(renewResponse))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		var err error
+		client, err = api.NewClient(&api.Config{Address: fakeVault.URL})
+		Expect(err).ToNot(HaveOccurred())
+		client.SetToken("static-token")
+	})
+
+	AfterEach(func() {
+		fakeVault.Close()
+	})
+
+	It("looks up and renews a static token and stops cleanly", func() {
+		lookupResponse = `{"data": {"ttl": 3600, "renewable": true}}`
+		renewResponse = `{"auth": {"client_token": "static-token", "lease_duration": 7200, "renewable": true}}`
+
+		tokenManager := vault.NewTokenManager(client, config.VaultConfiguration{RenewalInterval: 7200}, nil)
+		tokenManager.Start(nil)
+
+		Eventually(func() int {
+			return tokenManager.Status().Ttl
+		}).Should(BeNumerically(">", 3600))
+		status := tokenManager.Status()
+		Expect(status.State).To(Equal(vault.TokenStateActive))
+		Expect(status.Renewable).To(BeTrue())
+		Expect(status.ExpiresAt).ToNot(BeNil())
+
+		tokenManager.Stop()
+		Expect(tokenManager.Status().State).To(Equal(vault.TokenStateStopped))
+		// stopping twice is harmless
+		tokenManager.Stop()
+	})
+
+	It("reports a token that can't be renewed as expired once its ttl runs out", func() {
+		lookupResponse = `{"data": {"ttl": 1, "renewable": false}}`
+
+		tokenManager := vault.NewTokenManager(client, config.VaultConfiguration{RenewalInterval: 3600}, nil)
+		tokenManager.Start(nil)
+		defer tokenManager.Stop()
+
+		Eventually(func() string {
+			return tokenManager.Status().State
+		}, 3*time.Second).Should(Equal(vault.TokenStateExpired))
+		Expect(tokenManager.Usable()).To(BeFalse())
+	})
+
+	It("logs in again when the token reaches its max ttl", func() {
+		// renewals are capped far below the ttl the login granted, like a token running into its max ttl
+		renewResponse = `{"auth": {"client_token": "login-token", "lease_duration": 1, "renewable": true}}`
+		authenticator := &countingAuthenticator{
+			auth: api.SecretAuth{ClientToken: "login-token", LeaseDuration: 60, Renewable: true},
+		}
+
+		tokenManager := vault.NewTokenManager(client, config.VaultConfiguration{RenewalInterval: 60}, authenticator)
+		tokenManager.Start(nil)
+		defer tokenManager.Stop()
+
+		Eventually(func() int32 {
+			return atomic.LoadInt32(&authenticator.logins)
+		}).Should(BeNumerically(">=", 2))
+		Expect(client.Token()).To(Equal("login-token"))
+	})
+})
@@ -0,0 +1,62 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/cloudfoundry-community/bosh-vault/config"
+	"strconv"
+	"strings"
+)
+
+// Encrypts the plaintext with a named transit key, the ciphertext is prefixed with the key version, e.g. vault:v1:...
+func (v *Vault) TransitEncrypt(mount string, key string, plaintext []byte) (string, error) {
+	return v.transitWrite(mount, "encrypt", key, map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}, "ciphertext")
+}
+
+func (v *Vault) TransitDecrypt(mount string, key string, ciphertext string) ([]byte, error) {
+	encodedPlaintext, err := v.transitWrite(mount, "decrypt", key, map[string]interface{}{
+		"ciphertext": ciphertext,
+	}, "plaintext")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encodedPlaintext)
+}
+
+// Re-encrypts the ciphertext with the latest version of the key without exposing the plaintext
+func (v *Vault) TransitRewrap(mount string, key string, ciphertext string) (string, error) {
+	return v.transitWrite(mount, "rewrap", key, map[string]interface{}{
+		"ciphertext": ciphertext,
+	}, "ciphertext")
+}
+
+// The key version a transit ciphertext was encrypted with
+func TransitKeyVersion(ciphertext string) (int, error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return 0, errors.New("malformed transit ciphertext")
+	}
+	return strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+}
+
+func (v *Vault) transitWrite(mount string, operation string, key string, data map[string]interface{}, field string) (string, error) {
+	if mount == "" {
+		mount = config.DefaultTransitMount
+	}
+	path := fmt.Sprintf("%s/%s/%s", strings.Trim(mount, "/"), operation, key)
+	response, err := v.Client.Logical().Write(path, data)
+	if err != nil {
+		return "", err
+	}
+	if response == nil {
+		return "", errors.New(fmt.Sprintf("no response from %s", path))
+	}
+	value, ok := response.Data[field].(string)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("no %s in the response from %s", field, path))
+	}
+	return value, nil
+}
@@ -16,6 +16,9 @@ import (
 
 const DefaultVaultRenewalIntervalSeconds = 3600
 
+// how long to wait before retrying a failed login, the current token may still be valid for a while
+const loginRetryInterval = 10 * time.Second
+
 func GetVault(vaultConfig config.VaultConfiguration) (Vault, error) {
 	var vault Vault
 
@@ -27,6 +30,14 @@ func GetVault(vaultConfig config.VaultConfiguration) (Vault, error) {
 		vaultConfig.Mount = config.DefaultVaultMount
 	}
 
+	err := config.ValidateDeletePolicy(vaultConfig.DeletePolicy)
+	if err != nil {
+		return vault, err
+	}
+	if vaultConfig.DeletePolicy == "" {
+		vaultConfig.DeletePolicy = config.DefaultVaultDeletePolicy
+	}
+
 	vault.Config = vaultConfig
 	// Get the SystemCertPool, continue with an empty pool on error
 	rootCAs, _ := x509.SystemCertPool()
@@ -51,6 +62,20 @@ func GetVault(vaultConfig config.VaultConfiguration) (Vault, error) {
 		RootCAs:            rootCAs,
 	}
 
+	authenticator, err := GetAuthenticator(vaultConfig.Auth)
+	if err != nil {
+		return vault, err
+	}
+	if vaultConfig.TokenFile != "" {
+		if authenticator != nil {
+			return vault, errors.New("a vault tokenfile can't be combined with an auth method")
+		}
+		authenticator = &TokenFileAuthenticator{Path: vaultConfig.TokenFile}
+	}
+	if certAuthenticator, ok := authenticator.(*CertAuthenticator); ok {
+		tlsConfig.GetClientCertificate = certAuthenticator.GetClientCertificate
+	}
+
 	// Setup a custom transport that trusts our UAA Ca as well as the system's trusted certs
 	customTransport := &http.Transport{TLSClientConfig: tlsConfig}
 	customHttpClient := &http.Client{
@@ -69,26 +94,52 @@ func GetVault(vaultConfig config.VaultConfiguration) (Vault, error) {
 		return vault, err
 	}
 
-	clientInstance.SetToken(vaultConfig.Token)
 	clientInstance.SetClientTimeout(time.Duration(vaultConfig.Timeout) * time.Second)
+	// the namespace header is sent with every request the client makes, including logins, renewals, and health checks
+	if vaultConfig.Namespace != "" {
+		clientInstance.SetNamespace(vaultConfig.Namespace)
+	}
 
 	vault.Client = clientInstance
 
-	ticker := time.NewTicker(time.Duration(vaultConfig.RenewalInterval) * time.Second)
-	go func() {
-		for _ = range ticker.C {
-			_, err := vault.Client.Auth().Token().RenewSelf(vaultConfig.RenewalInterval)
-			if err != nil {
-				logger.Log.Errorf("Problem renewing token for %s, will try again in %d seconds", vaultConfig.Address, vaultConfig.RenewalInterval)
-			}
+	var auth *api.SecretAuth
+	var loginErr error
+	if authenticator == nil {
+		clientInstance.SetToken(vaultConfig.Token)
+	} else {
+		auth, loginErr = authenticator.Login(clientInstance)
+		if loginErr != nil {
+			logger.Log.Errorf("could not log in to Vault server at %s with %s auth, %s", vaultConfig.Address, authMethodName(vaultConfig), loginErr)
+			auth = nil
+		} else {
+			clientInstance.SetToken(auth.ClientToken)
 		}
-	}()
-	return vault, nil
+	}
+
+	// without auth the manager logs in itself and keeps retrying every loginRetryInterval until it succeeds, the login
+	// error is still returned so callers can decide whether to wait for it
+	vault.TokenManager = NewTokenManager(clientInstance, vaultConfig, authenticator)
+	vault.TokenManager.Start(auth)
+	return vault, loginErr
 }
 
 type Vault struct {
-	Client *api.Client
-	Config config.VaultConfiguration
+	Client       *api.Client
+	Config       config.VaultConfiguration
+	TokenManager *TokenManager
+	// the child namespace set by InNamespace, with a trailing /
+	childNamespace string
+}
+
+// Returns a Vault whose KV requests target the mount inside a child namespace of the client's namespace. Vault
+// Enterprise accepts namespaces as a path prefix so the client, and with it the token and its renewal, is shared.
+func (v Vault) InNamespace(namespace string) Vault {
+	namespace = strings.Trim(namespace, "/")
+	if namespace != "" {
+		v.Config.Mount = fmt.Sprintf("%s/%s", namespace, v.Config.Mount)
+		v.childNamespace = v.childNamespace + namespace + "/"
+	}
+	return v
 }
 
 func (v *Vault) exists(path string) bool {
@@ -107,7 +158,7 @@ func (v *Vault) exists(path string) bool {
 }
 
 func (v *Vault) ExistsV1(name string) bool {
-	path := fmt.Sprintf("/v1/%s%s", v.Config.Mount, v.sanitizeName(name))
+	path := fmt.Sprintf("/v1/%s", v.parseV1Path(name))
 	return v.exists(path)
 }
 
@@ -134,6 +185,36 @@ func (v *Vault) Delete(name string) error {
 	return err
 }
 
+// Soft deletes specific versions, Delete only ever soft deletes the latest one
+func (v *Vault) DeleteVersions(name string, versions []int) error {
+	_, err := v.Client.Logical().Write(v.parseDeletePath(name), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// Permanently removes every version of the secret along with its history
+func (v *Vault) DeleteMetadata(name string) error {
+	_, err := v.Client.Logical().Delete(v.parseMetaDataPath(name))
+	return err
+}
+
+// Restores soft deleted versions, destroyed versions can't be recovered
+func (v *Vault) Undelete(name string, versions []int) error {
+	_, err := v.Client.Logical().Write(v.parseUndeletePath(name), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// Permanently removes the data of the versions, their metadata is kept and they are reported as destroyed
+func (v *Vault) Destroy(name string, versions []int) error {
+	_, err := v.Client.Logical().Write(v.parseDestroyPath(name), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
 func (v *Vault) GetMetadata(name string) (map[string]interface{}, error) {
 	metadataPath := v.parseMetaDataPath(name)
 	metadata, err := v.Client.Logical().Read(metadataPath)
@@ -148,6 +229,35 @@ func (v *Vault) GetMetadata(name string) (map[string]interface{}, error) {
 	return metadata.Data, nil
 }
 
+// Lists the keys directly under the given path, keys ending in a / are "folders" that contain more keys
+func (v *Vault) List(name string) ([]string, error) {
+	listResponse, err := v.Client.Logical().List(v.parseMetaDataPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return listKeys(listResponse), nil
+}
+
+func listKeys(listResponse *api.Secret) []string {
+	keys := make([]string, 0)
+	if listResponse == nil {
+		return keys
+	}
+
+	rawKeys, ok := listResponse.Data["keys"].([]interface{})
+	if !ok {
+		return keys
+	}
+
+	for _, rawKey := range rawKeys {
+		if key, ok := rawKey.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
 func (v *Vault) Set(name string, value interface{}) (map[string]interface{}, error) {
 	path := v.parseDataPath(name)
 	response, err := v.Client.Logical().Write(path, map[string]interface{}{
@@ -166,7 +276,24 @@ func (v *Vault) Healthy() bool {
 		logger.Log.Errorf("problem checking health of vault %s: %s", v.Config.Address, err)
 		return false
 	}
-	return healthResponse.Initialized && !healthResponse.Sealed
+	return healthResponse.Initialized && !healthResponse.Sealed && v.TokenManager.Usable()
+}
+
+type Status struct {
+	Address string      `json:"address"`
+	Token   TokenStatus `json:"token"`
+}
+
+func (v *Vault) Status() Status {
+	return Status{
+		Address: v.Config.Address,
+		Token:   v.TokenManager.Status(),
+	}
+}
+
+// Stops renewing the token, the client can still be used until the token expires
+func (v *Vault) Close() {
+	v.TokenManager.Stop()
 }
 
 func (v *Vault) sanitizeName(name string) string {
@@ -186,3 +313,15 @@ func (v *Vault) parseDataPath(name string) string {
 func (v *Vault) parseMetaDataPath(name string) string {
 	return fmt.Sprintf("%s/metadata%s", v.Config.Mount, v.sanitizeName(name))
 }
+
+func (v *Vault) parseDeletePath(name string) string {
+	return fmt.Sprintf("%s/delete%s", v.Config.Mount, v.sanitizeName(name))
+}
+
+func (v *Vault) parseUndeletePath(name string) string {
+	return fmt.Sprintf("%s/undelete%s", v.Config.Mount, v.sanitizeName(name))
+}
+
+func (v *Vault) parseDestroyPath(name string) string {
+	return fmt.Sprintf("%s/destroy%s", v.Config.Mount, v.sanitizeName(name))
+}
@@ -2,13 +2,17 @@ package vault_test
 
 import (
 	"github.com/cloudfoundry-community/bosh-vault/config"
-	"github.com/cloudfoundry-community/bosh-vault/store"
+	"github.com/cloudfoundry-community/bosh-vault/logger"
+	"github.com/cloudfoundry-community/bosh-vault/store/storetest"
 	"github.com/cloudfoundry-community/bosh-vault/vault"
 	"github.com/hashicorp/vault-plugin-secrets-kv"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/credential/approle"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
 	hashiVault "github.com/hashicorp/vault/vault"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -16,30 +20,31 @@ import (
 )
 
 var healthyVault vault.Vault
+var healthyVaultAddress string
 
-var seedForKnownMetaDataTest = store.VaultTestData{
+var seedForKnownMetaDataTest = storetest.VaultTestData{
 	Path: "some_entry",
 	Value: map[string]interface{}{
 		"value": "who cares",
 	},
 }
 
-var seedData = []store.VaultTestData{
-	store.VaultTestData{
+var seedData = []storetest.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_password",
 		Value: map[string]interface{}{
 			"value": "$$$$$wakawakawaka$$$$$",
 		},
 		Seed: false, // A test expects to write this in for the first time
 	},
-	store.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_value",
 		Value: map[string]interface{}{
 			"value": "theBestValue",
 		},
 		Seed: true,
 	},
-	store.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_value2",
 		Value: map[string]interface{}{
 			"value":       "theBestValue",
@@ -48,7 +53,7 @@ var seedData = []store.VaultTestData{
 		},
 		Seed: true,
 	},
-	store.VaultTestData{
+	storetest.VaultTestData{
 		Path: "some_unicode",
 		Value: map[string]interface{}{
 			"value": "Ω≈ç√∫˜µ≤≥÷",
@@ -58,12 +63,18 @@ var seedData = []store.VaultTestData{
 
 func TestVault(t *testing.T) {
 	RegisterFailHandler(Fail)
+	// Make sure logger singleton is available
+	logger.Log = logrus.New()
+	logger.Log.Out = ioutil.Discard
 
 	var err error
 	core, _, token := hashiVault.TestCoreUnsealedWithConfig(t, &hashiVault.CoreConfig{
 		LogicalBackends: map[string]logical.Factory{
 			"kv": kv.Factory,
 		},
+		CredentialBackends: map[string]logical.Factory{
+			"approle": approle.Factory,
+		},
 	})
 
 	listener, addr := http.TestServer(t, core)
@@ -82,6 +93,7 @@ func TestVault(t *testing.T) {
 
 	Expect(err).NotTo(HaveOccurred())
 	healthyVault = vc
+	healthyVaultAddress = addr
 
 	RunSpecs(t, "Vault Suite")
 